@@ -9,6 +9,7 @@ import (
 	"fluent-life-backend/internal/hub"
 	"fluent-life-backend/internal/middleware"
 	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/services"
 	"fluent-life-backend/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -45,6 +46,9 @@ func main() {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	// 启动后台定时任务
+	services.StartBackgroundJobs(db, cfg)
+
 	// 设置 Gin 模式
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -63,21 +67,41 @@ func main() {
 		response.Success(c, gin.H{"status": "ok"}, "服务运行正常")
 	})
 
+	// 访问令牌验证公钥
+	jwksHandler := handlers.NewJWKSHandler(cfg)
+	r.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	// 初始化 WebSocket Hub
 	roomHub := hub.NewRoomHub()
+	roomHub.MatchFilter = services.NewPrivacyService(db).CanBeMatched
 	go roomHub.Run()
 
 	// 初始化处理器
-	authHandler := handlers.NewAuthHandler(db, cfg)
+	authHandler := handlers.NewAuthHandler(db, cfg, roomHub)
 	userHandler := handlers.NewUserHandler(db, cfg)
+	sessionHandler := handlers.NewSessionHandler(db, cfg, roomHub)
 	trainingHandler := handlers.NewTrainingHandler(db, cfg)
 	aiHandler := handlers.NewAIHandler(db, cfg)
 	communityHandler := handlers.NewCommunityHandler(db)
 	achievementHandler := handlers.NewAchievementHandler(db)
 	practiceRoomHandler := handlers.NewPracticeRoomHandler(db, roomHub)
-	wsHandler := handlers.NewWebSocketHandler(roomHub, db, cfg.JWTSecret)
+	wsHandler := handlers.NewWebSocketHandler(roomHub, db, cfg)
 	followHandler := handlers.NewFollowHandler(db)
 	collectionHandler := handlers.NewCollectionHandler(db)
+	adminHandler := handlers.NewAdminHandler(db, cfg, roomHub)
+	accountHandler := handlers.NewAccountHandler(db, cfg)
+	oidcHandler := handlers.NewOIDCHandler(db, cfg)
+	twoFactorHandler := handlers.NewTwoFactorHandler(db, cfg)
+	accessTokenHandler := handlers.NewAccessTokenHandler(db, cfg)
+	privacyHandler := handlers.NewPrivacyHandler(db)
+	blockHandler := handlers.NewBlockHandler(db)
+	therapistHandler := handlers.NewTherapistHandler(db, cfg)
+	assignmentHandler := handlers.NewAssignmentHandler(db)
+	notificationHandler := handlers.NewNotificationHandler(db)
+	clinicalNoteHandler := handlers.NewClinicalNoteHandler(db)
+	goalHandler := handlers.NewGoalHandler(db)
+
+	authMiddleware := middleware.Auth(db, cfg)
 
 	// API 路由组
 	v1 := r.Group("/api/v1")
@@ -88,29 +112,97 @@ func main() {
 			auth.POST("/send-code", authHandler.SendCode)
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/login-by-code", authHandler.LoginByCode)
+			auth.POST("/2fa/verify", authHandler.VerifyTwoFactor)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/logout-all", authMiddleware, authHandler.LogoutAll)
+			auth.POST("/guest", authHandler.CreateGuest)
+			auth.POST("/upgrade", authMiddleware, authHandler.UpgradeGuest)
+			auth.POST("/identifier/revert", authHandler.RevertIdentifierChange)
+
+			// 第三方（OIDC）登录
+			auth.GET("/oidc/providers", oidcHandler.GetProviders)
+			auth.GET("/oidc/:provider/authorize", oidcHandler.Authorize)
+			auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
+			auth.POST("/oidc/:provider/callback", oidcHandler.Callback)
+		}
+
+		// 管理后台（版主及以上）
+		admin := v1.Group("/admin")
+		admin.Use(authMiddleware, middleware.RequireRole(models.RoleModerator, models.RoleAdmin))
+		{
+			admin.DELETE("/posts/:id", middleware.RequirePermission(models.PermDeleteAnyPost), adminHandler.DeletePost)
+			admin.DELETE("/comments/:id", middleware.RequirePermission(models.PermDeleteAnyComment), adminHandler.DeleteComment)
+			admin.POST("/practice-rooms/:id/close", middleware.RequirePermission(models.PermCloseAnyRoom), adminHandler.CloseRoom)
+			admin.POST("/users/:id/suspend", middleware.RequirePermission(models.PermSuspendUser), adminHandler.SuspendUser)
+			admin.POST("/users/:id/unsuspend", middleware.RequirePermission(models.PermSuspendUser), adminHandler.UnsuspendUser)
+			admin.PUT("/users/:id/role", middleware.RequirePermission(models.PermManageRoles), adminHandler.SetRole)
+			admin.GET("/audit-events", middleware.RequirePermission(models.PermViewAuditLog), adminHandler.GetAuditEvents)
 		}
 
 		// 需要认证的路由
 		authenticated := v1.Group("")
-		authenticated.Use(middleware.Auth(cfg.JWTSecret))
+		authenticated.Use(authMiddleware)
 		{
 			// 用户相关
 			users := authenticated.Group("/users")
 			{
 				users.GET("/profile", userHandler.GetProfile)
 				users.PUT("/profile", userHandler.UpdateProfile)
+				users.PUT("/password", authHandler.ChangePassword)
+				users.POST("/me/identifier/request", authHandler.RequestIdentifierChange)
+				users.POST("/me/identifier/confirm", authHandler.ConfirmIdentifierChange)
 				users.GET("/stats", userHandler.GetStats)
+				users.GET("/sessions", sessionHandler.GetSessions)
+				users.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+				users.POST("/me/export", accountHandler.ExportData)
+				users.DELETE("/me", accountHandler.DeleteAccount)
+				users.POST("/me/cancel-deletion", accountHandler.CancelDeletion)
+				users.GET("/me/identities", oidcHandler.GetIdentities)
+				users.POST("/me/identities/:provider", oidcHandler.LinkIdentity)
+				users.DELETE("/me/identities/:provider", oidcHandler.UnlinkIdentity)
+				users.GET("/me/2fa", twoFactorHandler.GetStatus)
+				users.POST("/me/2fa/enroll", twoFactorHandler.Enroll)
+				users.POST("/me/2fa/confirm", twoFactorHandler.Confirm)
+				users.POST("/me/2fa/disable", twoFactorHandler.Disable)
+				users.POST("/me/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+				users.GET("/me/tokens", accessTokenHandler.GetTokens)
+				users.POST("/me/tokens", accessTokenHandler.CreateToken)
+				users.DELETE("/me/tokens/:id", accessTokenHandler.RevokeToken)
+				users.GET("/me/privacy", privacyHandler.GetSettings)
+				users.PUT("/me/privacy", privacyHandler.UpdateSettings)
+				users.GET("/me/blocks", blockHandler.GetBlocks)
+				users.POST("/:id/block", blockHandler.BlockUser)
+				users.DELETE("/:id/block", blockHandler.UnblockUser)
+				users.POST("/:id/mute", blockHandler.MuteUser)
+				users.DELETE("/:id/mute", blockHandler.UnmuteUser)
+				users.GET("/me/therapists", therapistHandler.GetTherapists)
+				users.GET("/me/therapists/invite", therapistHandler.PreviewInvite)
+				users.POST("/me/therapists", therapistHandler.AcceptInvite)
+				users.PUT("/me/therapists/:id", therapistHandler.UpdateConsent)
+				users.DELETE("/me/therapists/:id", therapistHandler.RevokeTherapist)
+				users.GET("/me/assignments", assignmentHandler.GetMyAssignments)
+				users.POST("/me/assignments/:id/complete", assignmentHandler.CompleteAssignment)
+				users.GET("/me/clinical-notes", clinicalNoteHandler.GetSharedNotes)
 				users.GET("/:id", userHandler.GetUserProfileByID)
 			}
 
-			// 训练记录
-			training := authenticated.Group("/training")
+			// 训练记录（也可使用带 training:read / training:write 权限的个人访问令牌）
+			training := v1.Group("/training", middleware.ScopedAuth(db, cfg, "training"))
 			{
 				training.POST("/records", trainingHandler.CreateRecord)
 				training.GET("/records", trainingHandler.GetRecords)
 				training.GET("/stats", trainingHandler.GetStats)
 				training.GET("/meditation-progress", trainingHandler.GetMeditationProgress)
+				training.GET("/streak", trainingHandler.GetStreak)
+				training.POST("/goals", goalHandler.CreateGoal)
+				training.GET("/goals", goalHandler.GetGoals)
+				training.GET("/goals/:id", goalHandler.GetGoal)
+				training.PUT("/goals/:id", goalHandler.UpdateGoal)
+				training.DELETE("/goals/:id", goalHandler.DeleteGoal)
+				training.GET("/goals/:id/history", goalHandler.GetGoalHistory)
 				training.GET("/weekly-stats", trainingHandler.GetWeeklyStats)
 				training.GET("/skill-levels", trainingHandler.GetSkillLevels)
 				training.GET("/recommendations", trainingHandler.GetRecommendations)
@@ -127,8 +219,8 @@ func main() {
 				ai.POST("/analyze-speech", aiHandler.AnalyzeSpeech)
 			}
 
-			// 社区
-			community := authenticated.Group("/community")
+			// 社区（也可使用带 community:read / community:write 权限的个人访问令牌；游客只读）
+			community := v1.Group("/community", middleware.ScopedAuth(db, cfg, "community"), middleware.RequireWritePermission(models.PermParticipateCommunity))
 			{
 				community.GET("/posts", communityHandler.GetPosts)
 				community.POST("/posts", communityHandler.CreatePost)
@@ -146,8 +238,8 @@ func main() {
 				community.GET("/posts/:id/collection-count", communityHandler.GetPostCollectionCount)
 			}
 
-			// 关注功能
-			follow := authenticated.Group("/follow")
+			// 关注功能（游客只读）
+			follow := authenticated.Group("/follow", middleware.RequireWritePermission(models.PermParticipateCommunity))
 			{
 				follow.POST("/users/:id", followHandler.FollowUser)
 				follow.DELETE("/users/:id", followHandler.UnfollowUser)
@@ -166,6 +258,36 @@ func main() {
 				collection.POST("/toggle/:id", collectionHandler.ToggleCollectPost) // 新增的切换收藏状态路由
 			}
 
+			// 治疗师（查看客户数据需客户授权对应范围）
+			therapist := authenticated.Group("/therapist", middleware.RequirePermission(models.PermViewClients))
+			{
+				therapist.POST("/invites", therapistHandler.CreateInvite)
+				therapist.GET("/invites", therapistHandler.GetInvites)
+				therapist.DELETE("/invites/:id", therapistHandler.CancelInvite)
+				therapist.GET("/clients", therapistHandler.GetClients)
+				therapist.GET("/clients/:id", therapistHandler.GetClient)
+				therapist.GET("/clients/:id/records", therapistHandler.GetClientRecords)
+				therapist.GET("/clients/:id/meditation-progress", therapistHandler.GetClientMeditationProgress)
+				therapist.GET("/clients/:id/ai-conversation", therapistHandler.GetClientAIConversation)
+				therapist.POST("/clients/:id/assignments", assignmentHandler.CreateAssignment)
+				therapist.GET("/clients/:id/assignments", assignmentHandler.GetClientAssignments)
+				therapist.DELETE("/assignments/:id", assignmentHandler.CancelAssignment)
+				therapist.POST("/clients/:id/notes", clinicalNoteHandler.CreateNote)
+				therapist.GET("/clients/:id/notes", clinicalNoteHandler.GetClientNotes)
+				therapist.GET("/notes/:id", clinicalNoteHandler.GetNote)
+				therapist.PUT("/notes/:id", clinicalNoteHandler.UpdateNote)
+				therapist.DELETE("/notes/:id", clinicalNoteHandler.DeleteNote)
+				therapist.GET("/notes/:id/versions", clinicalNoteHandler.GetNoteVersions)
+			}
+
+			// 站内通知
+			notifications := authenticated.Group("/notifications")
+			{
+				notifications.GET("", notificationHandler.GetNotifications)
+				notifications.POST("/:id/read", notificationHandler.MarkRead)
+				notifications.POST("/read-all", notificationHandler.MarkAllRead)
+			}
+
 			// 成就系统
 			achievements := authenticated.Group("/achievements")
 			{
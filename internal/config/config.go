@@ -3,13 +3,26 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"fluent-life-backend/internal/meditation"
+	"fluent-life-backend/internal/oidc"
+	"fluent-life-backend/pkg/auth"
+
 	"github.com/spf13/viper"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
+// developmentTwoFactorKey 开发环境未设置 TWO_FACTOR_ENCRYPTION_KEY 时使用的 TOTP 加密密钥
+const developmentTwoFactorKey = "development-only-two-factor-encryption-key"
+
+// minTwoFactorKeyLength TWO_FACTOR_ENCRYPTION_KEY 的最短长度
+const minTwoFactorKeyLength = 32
+
 type Config struct {
 	Environment string `mapstructure:"ENVIRONMENT"`
 	Port        string `mapstructure:"PORT"`
@@ -24,17 +37,110 @@ type Config struct {
 	} `mapstructure:",squash"`
 
 	JWTSecret     string        `mapstructure:"JWT_SECRET"`
-	JWTExpiration time.Duration `mapstructure:"JWT_EXPIRATION"`
+	JWTExpiration time.Duration `mapstructure:"JWT_EXPIRATION"` // 访问令牌有效期（短期）
+
+	// 非对称签名密钥（Ed25519 / RS256）。都未配置时回退到 JWT_SECRET（HS256）
+	JWTKeys struct {
+		Dir            string        `mapstructure:"JWT_KEYS_DIR"`             // 密钥目录：<kid>.pem 私钥，<kid>.pub.pem 只用于验证的公钥，active 文件指定签名 kid
+		PrivateKey     string        `mapstructure:"JWT_PRIVATE_KEY"`          // PEM 格式私钥
+		KeyID          string        `mapstructure:"JWT_KEY_ID"`               // JWT_PRIVATE_KEY 的 kid
+		ActiveKeyID    string        `mapstructure:"JWT_ACTIVE_KEY_ID"`        // 签名使用的 kid，留空时读取 active 文件
+		AllowHS256     bool          `mapstructure:"JWT_ALLOW_HS256"`          // 启用非对称密钥后是否仍接受 HS256 令牌（迁移期）
+		ReloadInterval time.Duration `mapstructure:"JWT_KEYS_RELOAD_INTERVAL"` // 重新读取密钥目录的间隔
+	} `mapstructure:",squash"`
+	// 由以上配置加载的密钥
+	JWTKeySet *auth.KeySet `mapstructure:"-"`
+
+	// 刷新令牌有效期
+	RefreshTokenExpiration time.Duration `mapstructure:"REFRESH_TOKEN_EXPIRATION"`
 
 	// 验证码配置
 	CodeExpiration time.Duration `mapstructure:"CODE_EXPIRATION"`
+	CodeHashKey    string        `mapstructure:"CODE_HASH_KEY"` // 验证码摘要密钥，开发环境以外必须设置；开发环境留空时使用 JWT_SECRET
+	CodeLimits     struct {
+		MaxAttempts       int `mapstructure:"CODE_MAX_ATTEMPTS"`            // 单个验证码最多校验次数
+		IdentifierPerHour int `mapstructure:"CODE_IDENTIFIER_HOURLY_LIMIT"` // 每个手机号/邮箱每小时发送上限
+		IdentifierPerDay  int `mapstructure:"CODE_IDENTIFIER_DAILY_LIMIT"`  // 每个手机号/邮箱每天发送上限
+		IPPerHour         int `mapstructure:"CODE_IP_HOURLY_LIMIT"`         // 每个 IP 每小时发送上限
+		IPPerDay          int `mapstructure:"CODE_IP_DAILY_LIMIT"`          // 每个 IP 每天发送上限
+	} `mapstructure:",squash"`
+
+	// 注册时是否校验验证码（未显式设置时生产环境默认开启）
+	RequireRegisterCode bool `mapstructure:"REQUIRE_REGISTER_CODE"`
+	// 验证码登录时是否为未注册的手机号/邮箱自动创建账号
+	CodeLoginAutoRegister bool `mapstructure:"CODE_LOGIN_AUTO_REGISTER"`
+
+	// 登录防暴力破解
+	LoginLimits struct {
+		FreeAttempts     int           `mapstructure:"LOGIN_FREE_ATTEMPTS"`     // 同一账号允许的连续失败次数，超过后开始指数退避
+		IPFreeAttempts   int           `mapstructure:"LOGIN_IP_FREE_ATTEMPTS"`  // 同一 IP 允许的连续失败次数
+		BackoffBase      time.Duration `mapstructure:"LOGIN_BACKOFF_BASE"`      // 首次退避时长，之后每次失败翻倍
+		BackoffMax       time.Duration `mapstructure:"LOGIN_BACKOFF_MAX"`       // 退避时长上限
+		LockoutThreshold int           `mapstructure:"LOGIN_LOCKOUT_THRESHOLD"` // 连续失败达到该次数后锁定账号
+		LockoutDuration  time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`  // 账号锁定时长
+		AttemptWindow    time.Duration `mapstructure:"LOGIN_ATTEMPT_WINDOW"`    // 超过该时长无失败则重新计数
+	} `mapstructure:",squash"`
+
+	// 账号注销宽限期，期间可撤销
+	AccountDeletionGracePeriod time.Duration `mapstructure:"ACCOUNT_DELETION_GRACE_PERIOD"`
+
+	// OIDC 登录提供方。config.yaml 中用 OIDC_PROVIDERS 列表配置；
+	// 也可通过环境变量 OIDC_PROVIDER_NAMES=a,b 和 OIDC_<NAME>_ISSUER/CLIENT_ID/CLIENT_SECRET/REDIRECT_URL/SCOPES 配置
+	OIDCProviders []oidc.Config `mapstructure:"OIDC_PROVIDERS"`
+	// OIDC 授权请求（state）有效期
+	OIDCStateExpiration time.Duration `mapstructure:"OIDC_STATE_EXPIRATION"`
+
+	// TOTP 二次验证
+	TwoFactor struct {
+		Issuer              string        `mapstructure:"TWO_FACTOR_ISSUER"`               // 验证器 App 中显示的名称
+		EncryptionKey       string        `mapstructure:"TWO_FACTOR_ENCRYPTION_KEY"`       // TOTP 密钥落库加密用，开发环境以外必须设置（至少32个字符）；开发环境留空时使用内置的开发密钥
+		ChallengeExpiration time.Duration `mapstructure:"TWO_FACTOR_CHALLENGE_EXPIRATION"` // 登录挑战令牌有效期
+	} `mapstructure:",squash"`
+
+	// 更换邮箱/手机号后，原邮箱/手机号可以撤销变更的期限
+	IdentifierChangeRevertWindow time.Duration `mapstructure:"IDENTIFIER_CHANGE_REVERT_WINDOW"`
+
+	// 审计事件保留时长，超过后由后台任务清理
+	AuditRetention time.Duration `mapstructure:"AUDIT_RETENTION"`
+
+	// 每个用户可同时持有的个人访问令牌数量上限
+	PersonalAccessTokenLimit int `mapstructure:"PERSONAL_ACCESS_TOKEN_LIMIT"`
+
+	// 游客账号
+	Guest struct {
+		InactiveTTL   time.Duration `mapstructure:"GUEST_INACTIVE_TTL"`    // 超过该时长无任何活动的游客账号会被清理，0 表示不清理
+		IPHourlyLimit int           `mapstructure:"GUEST_IP_HOURLY_LIMIT"` // 同一 IP 每小时可创建的游客账号数量
+	} `mapstructure:",squash"`
+
+	// 冥想阶段定义文件，不存在时使用内置定义
+	MeditationStagesFile string `mapstructure:"MEDITATION_STAGES_FILE"`
+	// 由以上文件加载的阶段定义
+	MeditationStages *meditation.StageSet `mapstructure:"-"`
+
+	// 治疗师邀请客户关联的邀请链接有效期
+	TherapistInviteTTL time.Duration `mapstructure:"THERAPIST_INVITE_TTL"`
 
 	// AI 服务配置
 	GeminiAPIKey string `mapstructure:"GEMINI_API_KEY"`
 
 	// 短信/邮件服务配置（可选）
-	SMSProvider   string `mapstructure:"SMS_PROVIDER"`
-	EmailProvider string `mapstructure:"EMAIL_PROVIDER"`
+	SMSProvider   string `mapstructure:"SMS_PROVIDER"`   // http | outbox，留空则不发送
+	EmailProvider string `mapstructure:"EMAIL_PROVIDER"` // smtp | outbox，留空则不发送
+
+	Notify struct {
+		SMTPHost     string `mapstructure:"SMTP_HOST"`
+		SMTPPort     string `mapstructure:"SMTP_PORT"`
+		SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+		SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+		SMTPFrom     string `mapstructure:"SMTP_FROM"`
+
+		SMSEndpoint string `mapstructure:"SMS_ENDPOINT"`
+		SMSAPIKey   string `mapstructure:"SMS_API_KEY"`
+		SMSSender   string `mapstructure:"SMS_SENDER"`
+
+		OutboxPath  string `mapstructure:"NOTIFY_OUTBOX_PATH"`  // outbox 渠道写入的文件
+		MaxAttempts int    `mapstructure:"NOTIFY_MAX_ATTEMPTS"` // 发送失败时的最大尝试次数
+	} `mapstructure:",squash"`
 }
 
 func Load() (*Config, error) {
@@ -65,9 +171,68 @@ func Load() (*Config, error) {
 	// 从环境变量覆盖（优先级更高）
 	overrideFromEnv(&cfg)
 
+	cfg.OIDCProviders = append(cfg.OIDCProviders, oidcProvidersFromEnv()...)
+
+	keySet, err := auth.NewKeySet(auth.KeySetOptions{
+		Dir:           cfg.JWTKeys.Dir,
+		PrivateKeyPEM: cfg.JWTKeys.PrivateKey,
+		KeyID:         cfg.JWTKeys.KeyID,
+		ActiveKeyID:   cfg.JWTKeys.ActiveKeyID,
+		HMACSecret:    cfg.JWTSecret,
+		AllowHMAC:     cfg.JWTKeys.AllowHS256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading JWT keys: %w", err)
+	}
+	cfg.JWTKeySet = keySet
+
+	stages, err := meditation.Load(cfg.MeditationStagesFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MeditationStages = stages
+
+	// 验证码摘要密钥与 JWT_SECRET 分开，轮换 JWT_SECRET 不会使未过期的验证码失效；只有开发环境允许回退
+	if cfg.CodeHashKey == "" {
+		if cfg.Environment != "development" {
+			return nil, fmt.Errorf("CODE_HASH_KEY must be set outside development")
+		}
+		cfg.CodeHashKey = cfg.JWTSecret
+	}
+
+	// TOTP 加密密钥不从 JWT_SECRET 派生，否则轮换 JWT_SECRET 后已保存的 TOTP 密钥都无法解密；只有开发环境允许回退
+	if cfg.TwoFactor.EncryptionKey == "" {
+		if cfg.Environment != "development" {
+			return nil, fmt.Errorf("TWO_FACTOR_ENCRYPTION_KEY must be set outside development")
+		}
+		cfg.TwoFactor.EncryptionKey = developmentTwoFactorKey
+	}
+	if len(cfg.TwoFactor.EncryptionKey) < minTwoFactorKeyLength {
+		return nil, fmt.Errorf("TWO_FACTOR_ENCRYPTION_KEY must be at least %d characters long", minTwoFactorKeyLength)
+	}
+
+	if err := checkProductionSecrets(&cfg, keySet); err != nil {
+		return nil, err
+	}
+
+	if !viper.IsSet("REQUIRE_REGISTER_CODE") {
+		cfg.RequireRegisterCode = cfg.Environment == "production"
+	}
+
 	return &cfg, nil
 }
 
+// checkProductionSecrets 生产环境中仓库自带的默认 JWT_SECRET 不能用于 HS256 签名或验证
+func checkProductionSecrets(cfg *Config, keySet *auth.KeySet) error {
+	if cfg.Environment != "production" || cfg.JWTSecret != defaultJWTSecret {
+		return nil
+	}
+	if !keySet.Asymmetric() || cfg.JWTKeys.AllowHS256 {
+		return fmt.Errorf("JWT_SECRET must be changed (or JWT signing keys configured without JWT_ALLOW_HS256) in production")
+	}
+	return nil
+}
+
 func setDefaults() {
 	viper.SetDefault("ENVIRONMENT", "development")
 	viper.SetDefault("PORT", "8080")
@@ -77,9 +242,54 @@ func setDefaults() {
 	viper.SetDefault("DB_PASSWORD", "postgres")
 	viper.SetDefault("DB_NAME", "fluent_life")
 	viper.SetDefault("DB_SSLMODE", "disable")
-	viper.SetDefault("JWT_SECRET", "your-secret-key-change-in-production")
-	viper.SetDefault("JWT_EXPIRATION", "24h")
+	viper.SetDefault("JWT_SECRET", defaultJWTSecret)
+	viper.SetDefault("JWT_EXPIRATION", "15m")
+	viper.SetDefault("JWT_KEYS_DIR", "")
+	viper.SetDefault("JWT_PRIVATE_KEY", "")
+	viper.SetDefault("JWT_KEY_ID", "")
+	viper.SetDefault("JWT_ACTIVE_KEY_ID", "")
+	viper.SetDefault("JWT_ALLOW_HS256", false)
+	viper.SetDefault("JWT_KEYS_RELOAD_INTERVAL", "1m")
+	viper.SetDefault("REFRESH_TOKEN_EXPIRATION", "720h")
 	viper.SetDefault("CODE_EXPIRATION", "5m")
+	viper.SetDefault("CODE_HASH_KEY", "")
+	viper.SetDefault("CODE_MAX_ATTEMPTS", 5)
+	viper.SetDefault("CODE_IDENTIFIER_HOURLY_LIMIT", 5)
+	viper.SetDefault("CODE_IDENTIFIER_DAILY_LIMIT", 10)
+	viper.SetDefault("CODE_IP_HOURLY_LIMIT", 20)
+	viper.SetDefault("CODE_IP_DAILY_LIMIT", 50)
+	viper.SetDefault("CODE_LOGIN_AUTO_REGISTER", false)
+	viper.SetDefault("LOGIN_FREE_ATTEMPTS", 3)
+	viper.SetDefault("LOGIN_IP_FREE_ATTEMPTS", 20)
+	viper.SetDefault("LOGIN_BACKOFF_BASE", "30s")
+	viper.SetDefault("LOGIN_BACKOFF_MAX", "15m")
+	viper.SetDefault("LOGIN_LOCKOUT_THRESHOLD", 10)
+	viper.SetDefault("LOGIN_LOCKOUT_DURATION", "30m")
+	viper.SetDefault("LOGIN_ATTEMPT_WINDOW", "24h")
+	viper.SetDefault("ACCOUNT_DELETION_GRACE_PERIOD", "168h")
+	viper.SetDefault("OIDC_STATE_EXPIRATION", "10m")
+	viper.SetDefault("TWO_FACTOR_ISSUER", "流畅生活")
+	viper.SetDefault("TWO_FACTOR_ENCRYPTION_KEY", "")
+	viper.SetDefault("TWO_FACTOR_CHALLENGE_EXPIRATION", "5m")
+	viper.SetDefault("IDENTIFIER_CHANGE_REVERT_WINDOW", "168h")
+	viper.SetDefault("AUDIT_RETENTION", "8760h")
+	viper.SetDefault("PERSONAL_ACCESS_TOKEN_LIMIT", 20)
+	viper.SetDefault("GUEST_INACTIVE_TTL", "720h")
+	viper.SetDefault("GUEST_IP_HOURLY_LIMIT", 10)
+	viper.SetDefault("THERAPIST_INVITE_TTL", "168h")
+	viper.SetDefault("MEDITATION_STAGES_FILE", "configs/meditation_stages.yaml")
+	viper.SetDefault("SMS_PROVIDER", "")
+	viper.SetDefault("EMAIL_PROVIDER", "")
+	viper.SetDefault("SMTP_HOST", "")
+	viper.SetDefault("SMTP_PORT", "587")
+	viper.SetDefault("SMTP_USERNAME", "")
+	viper.SetDefault("SMTP_PASSWORD", "")
+	viper.SetDefault("SMTP_FROM", "")
+	viper.SetDefault("SMS_ENDPOINT", "")
+	viper.SetDefault("SMS_API_KEY", "")
+	viper.SetDefault("SMS_SENDER", "")
+	viper.SetDefault("NOTIFY_OUTBOX_PATH", "logs/outbox.jsonl")
+	viper.SetDefault("NOTIFY_MAX_ATTEMPTS", 3)
 }
 
 func overrideFromEnv(cfg *Config) {
@@ -120,11 +330,105 @@ func overrideFromEnv(cfg *Config) {
 			cfg.JWTExpiration = d
 		}
 	}
+	if exp := os.Getenv("IDENTIFIER_CHANGE_REVERT_WINDOW"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.IdentifierChangeRevertWindow = d
+		}
+	}
+	if exp := os.Getenv("AUDIT_RETENTION"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.AuditRetention = d
+		}
+	}
+	if exp := os.Getenv("JWT_KEYS_RELOAD_INTERVAL"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.JWTKeys.ReloadInterval = d
+		}
+	}
+	if exp := os.Getenv("REFRESH_TOKEN_EXPIRATION"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.RefreshTokenExpiration = d
+		}
+	}
 	if exp := os.Getenv("CODE_EXPIRATION"); exp != "" {
 		if d, err := time.ParseDuration(exp); err == nil {
 			cfg.CodeExpiration = d
 		}
 	}
+	if exp := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.AccountDeletionGracePeriod = d
+		}
+	}
+	if exp := os.Getenv("GUEST_INACTIVE_TTL"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.Guest.InactiveTTL = d
+		}
+	}
+	if exp := os.Getenv("THERAPIST_INVITE_TTL"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.TherapistInviteTTL = d
+		}
+	}
+	if exp := os.Getenv("OIDC_STATE_EXPIRATION"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.OIDCStateExpiration = d
+		}
+	}
+	if exp := os.Getenv("TWO_FACTOR_CHALLENGE_EXPIRATION"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.TwoFactor.ChallengeExpiration = d
+		}
+	}
+	if exp := os.Getenv("LOGIN_BACKOFF_BASE"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.LoginLimits.BackoffBase = d
+		}
+	}
+	if exp := os.Getenv("LOGIN_BACKOFF_MAX"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.LoginLimits.BackoffMax = d
+		}
+	}
+	if exp := os.Getenv("LOGIN_LOCKOUT_DURATION"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.LoginLimits.LockoutDuration = d
+		}
+	}
+	if exp := os.Getenv("LOGIN_ATTEMPT_WINDOW"); exp != "" {
+		if d, err := time.ParseDuration(exp); err == nil {
+			cfg.LoginLimits.AttemptWindow = d
+		}
+	}
+}
+
+// oidcProvidersFromEnv 从环境变量读取 OIDC 提供方配置
+func oidcProvidersFromEnv() []oidc.Config {
+	names := os.Getenv("OIDC_PROVIDER_NAMES")
+	if names == "" {
+		return nil
+	}
+
+	var providers []oidc.Config
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			provider.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+		providers = append(providers, provider)
+	}
+	return providers
 }
 
 func InitDB(cfg *Config) (*gorm.DB, error) {
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AccessTokenHandler struct {
+	tokenService *services.PersonalAccessTokenService
+}
+
+func NewAccessTokenHandler(db *gorm.DB, cfg *config.Config) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		tokenService: services.NewPersonalAccessTokenService(db, cfg),
+	}
+}
+
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=3650"` // 0 表示不过期
+}
+
+// GetTokens 获取当前用户的个人访问令牌及可选的权限范围
+func (h *AccessTokenHandler) GetTokens(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	tokens, err := h.tokenService.List(userID)
+	if err != nil {
+		response.InternalError(c, "获取访问令牌失败")
+		return
+	}
+
+	response.Success(c, gin.H{"tokens": tokens, "available_scopes": models.TokenScopes}, "获取成功")
+}
+
+// CreateToken 创建个人访问令牌，令牌明文只在此时返回一次
+func (h *AccessTokenHandler) CreateToken(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	expiresIn := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, plaintext, err := h.tokenService.Create(userID, req.Name, req.Scopes, expiresIn)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"token": plaintext, "access_token": token}, "创建成功，令牌只显示这一次，请妥善保存")
+}
+
+// RevokeToken 吊销个人访问令牌
+func (h *AccessTokenHandler) RevokeToken(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	tokenID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的令牌ID")
+		return
+	}
+
+	if err := h.tokenService.Revoke(userID, tokenID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "令牌不存在或已吊销")
+			return
+		}
+		response.InternalError(c, "吊销令牌失败")
+		return
+	}
+
+	response.Success(c, nil, "令牌已吊销")
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AccountHandler struct {
+	accountService *services.AccountService
+	auditService   *services.AuditService
+}
+
+func NewAccountHandler(db *gorm.DB, cfg *config.Config) *AccountHandler {
+	return &AccountHandler{
+		accountService: services.NewAccountService(db, cfg),
+		auditService:   services.NewAuditService(db),
+	}
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"` // 设置过密码的账号必填
+}
+
+// ExportData 导出个人数据，默认返回 ZIP 文件，?format=json 时直接返回 JSON
+func (h *AccountHandler) ExportData(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	export, err := h.accountService.ExportData(userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "用户不存在")
+			return
+		}
+		utils.APILog("[AccountHandler.ExportData] ❌ 导出用户 %s 数据失败: %v", userID, err)
+		response.InternalError(c, "导出数据失败")
+		return
+	}
+
+	if c.Query("format") == "json" {
+		response.Success(c, export, "导出成功")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.accountService.WriteExportZip(&buf, export); err != nil {
+		utils.APILog("[AccountHandler.ExportData] ❌ 打包用户 %s 数据失败: %v", userID, err)
+		response.InternalError(c, "导出数据失败")
+		return
+	}
+
+	filename := fmt.Sprintf("fluent-life-export-%s.zip", export.ExportedAt.Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(200, "application/zip", buf.Bytes())
+}
+
+// DeleteAccount 申请注销账号（宽限期内可撤销）
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	user, err := h.accountService.RequestDeletion(userID, req.Password)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "用户不存在")
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	h.auditService.Record(deviceInfo(c, ""), models.AuditEvent{
+		ActorID:    &userID,
+		Action:     models.AuditAccountDeletionRequested,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID.String(),
+		Metadata:   models.JSONB{"scheduled_at": user.DeletionScheduledAt},
+	})
+	response.Success(c, gin.H{"deletion_scheduled_at": user.DeletionScheduledAt}, "已申请注销，宽限期内可撤销")
+}
+
+// CancelDeletion 撤销注销申请
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	if err := h.accountService.CancelDeletion(userID); err != nil {
+		if err == services.ErrDeletionNotRequested {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.InternalError(c, "撤销注销失败")
+		return
+	}
+
+	h.auditService.Record(deviceInfo(c, ""), models.AuditEvent{
+		ActorID:    &userID,
+		Action:     models.AuditAccountDeletionCancelled,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID.String(),
+	})
+	response.Success(c, nil, "已撤销注销申请")
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/hub"
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AdminHandler struct {
+	adminService *services.AdminService
+	auditService *services.AuditService
+	roomHub      *hub.RoomHub
+}
+
+func NewAdminHandler(db *gorm.DB, cfg *config.Config, roomHub *hub.RoomHub) *AdminHandler {
+	return &AdminHandler{
+		adminService: services.NewAdminService(db, cfg),
+		auditService: services.NewAuditService(db),
+		roomHub:      roomHub,
+	}
+}
+
+type SuspendUserRequest struct {
+	Reason        string `json:"reason" binding:"required,max=500"`
+	DurationHours int    `json:"duration_hours" binding:"min=0"` // 0 表示无限期
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// DeletePost 删除任意帖子
+func (h *AdminHandler) DeletePost(c *gin.Context) {
+	actorID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的帖子ID")
+		return
+	}
+
+	if err := h.adminService.DeletePost(actorID, postID, deviceInfo(c, "")); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "帖子不存在")
+			return
+		}
+		response.InternalError(c, "删除失败")
+		return
+	}
+
+	response.Success(c, nil, "删除成功")
+}
+
+// DeleteComment 删除任意评论
+func (h *AdminHandler) DeleteComment(c *gin.Context) {
+	actorID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	commentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的评论ID")
+		return
+	}
+
+	if err := h.adminService.DeleteComment(actorID, commentID, deviceInfo(c, "")); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "评论不存在")
+			return
+		}
+		response.InternalError(c, "删除失败")
+		return
+	}
+
+	response.Success(c, nil, "删除成功")
+}
+
+// CloseRoom 强制关闭对练房，并断开房间内的 WebSocket 连接
+func (h *AdminHandler) CloseRoom(c *gin.Context) {
+	actorID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "房间ID格式错误")
+		return
+	}
+
+	if err := h.adminService.CloseRoom(actorID, roomID, deviceInfo(c, "")); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "房间不存在")
+			return
+		}
+		response.InternalError(c, "关闭房间失败")
+		return
+	}
+	h.roomHub.CloseRoom(roomID.String(), "closed_by_moderator")
+
+	response.Success(c, nil, "房间已关闭")
+}
+
+// SuspendUser 封禁用户，立即结束其所有登录会话
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	actorID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	var req SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	var until *time.Time
+	if req.DurationHours > 0 {
+		t := time.Now().Add(time.Duration(req.DurationHours) * time.Hour)
+		until = &t
+	}
+
+	user, err := h.adminService.SuspendUser(actorID, userID, until, req.Reason, deviceInfo(c, ""))
+	if err != nil {
+		h.handleUserError(c, err, "封禁失败")
+		return
+	}
+	h.roomHub.DisconnectUser(userID.String())
+
+	response.Success(c, user, "用户已封禁")
+}
+
+// UnsuspendUser 解除封禁
+func (h *AdminHandler) UnsuspendUser(c *gin.Context) {
+	actorID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	user, err := h.adminService.UnsuspendUser(actorID, userID, deviceInfo(c, ""))
+	if err != nil {
+		h.handleUserError(c, err, "解除封禁失败")
+		return
+	}
+
+	response.Success(c, user, "已解除封禁")
+}
+
+// SetRole 修改用户角色（仅管理员）
+func (h *AdminHandler) SetRole(c *gin.Context) {
+	actorID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	var req SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	user, err := h.adminService.SetRole(actorID, userID, req.Role, deviceInfo(c, ""))
+	if err != nil {
+		h.handleUserError(c, err, "修改角色失败")
+		return
+	}
+
+	response.Success(c, user, "角色已更新")
+}
+
+// GetAuditEvents 分页查询审计事件，支持 actor_id、action（以 . 结尾按前缀匹配）、target_type、target_id、ip、since、until（RFC3339）过滤
+func (h *AdminHandler) GetAuditEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	filter := services.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		IP:         c.Query("ip"),
+	}
+	if actor := c.Query("actor_id"); actor != "" {
+		actorID, err := uuid.Parse(actor)
+		if err != nil {
+			response.BadRequest(c, "无效的 actor_id")
+			return
+		}
+		filter.ActorID = &actorID
+	}
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			response.BadRequest(c, "无效的时间格式: "+param.name+"（需要 RFC3339）")
+			return
+		}
+		*param.dest = &t
+	}
+
+	events, total, err := h.auditService.List(filter, page, pageSize)
+	if err != nil {
+		response.InternalError(c, "获取审计日志失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"events":    events,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}, "获取成功")
+}
+
+func (h *AdminHandler) handleUserError(c *gin.Context, err error, fallback string) {
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		response.NotFound(c, "用户不存在")
+	case err == services.ErrInsufficientRank:
+		response.Forbidden(c, err.Error())
+	case err == services.ErrInvalidRole:
+		response.BadRequest(c, err.Error())
+	default:
+		response.InternalError(c, fallback)
+	}
+}
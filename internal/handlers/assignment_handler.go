@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AssignmentHandler struct {
+	assignmentService *services.AssignmentService
+}
+
+func NewAssignmentHandler(db *gorm.DB) *AssignmentHandler {
+	return &AssignmentHandler{
+		assignmentService: services.NewAssignmentService(db),
+	}
+}
+
+type CreateAssignmentRequest struct {
+	Type               string     `json:"type" binding:"required,oneof=meditation airflow exposure practice"`
+	TargetCount        int        `json:"target_count" binding:"min=0,max=100"`         // 默认 1 次
+	MinDurationMinutes int        `json:"min_duration_minutes" binding:"min=0,max=600"` // 每次训练的最少时长，0 表示不限
+	Notes              string     `json:"notes"`
+	StartsAt           *time.Time `json:"starts_at"` // 默认从现在开始
+	DueAt              time.Time  `json:"due_at" binding:"required"`
+}
+
+// assignmentStatusQuery 读取并校验 ?status= 过滤条件
+func assignmentStatusQuery(c *gin.Context) (string, bool) {
+	status := c.Query("status")
+	if status != "" && !models.IsValidAssignmentStatus(status) {
+		response.BadRequest(c, "无效的作业状态")
+		return "", false
+	}
+	return status, true
+}
+
+// CreateAssignment 治疗师给客户布置作业
+func (h *AssignmentHandler) CreateAssignment(c *gin.Context) {
+	therapistID, clientID, ok := clientParams(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	assignment, err := h.assignmentService.Create(therapistID, clientID, services.AssignmentInput{
+		Type:        req.Type,
+		TargetCount: req.TargetCount,
+		MinDuration: req.MinDurationMinutes * 60,
+		Notes:       req.Notes,
+		StartsAt:    req.StartsAt,
+		DueAt:       req.DueAt,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrClientNotLinked) {
+			response.NotFound(c, err.Error())
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, assignment, "作业已布置")
+}
+
+// GetClientAssignments 治疗师查看给某个客户布置的作业
+func (h *AssignmentHandler) GetClientAssignments(c *gin.Context) {
+	therapistID, clientID, ok := clientParams(c)
+	if !ok {
+		return
+	}
+	status, ok := assignmentStatusQuery(c)
+	if !ok {
+		return
+	}
+	page, pageSize := utils.GetPaginationParams(c)
+
+	assignments, total, err := h.assignmentService.ListForTherapist(therapistID, clientID, status, page, pageSize)
+	if err != nil {
+		respondClientError(c, err, "获取作业失败")
+		return
+	}
+
+	response.Success(c, gin.H{"assignments": assignments, "total": total, "page": page, "page_size": pageSize}, "获取成功")
+}
+
+// CancelAssignment 治疗师取消未完成的作业
+func (h *AssignmentHandler) CancelAssignment(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	assignmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的作业ID")
+		return
+	}
+
+	if err := h.assignmentService.Cancel(userID, assignmentID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "作业不存在或已结束")
+			return
+		}
+		response.InternalError(c, "取消作业失败")
+		return
+	}
+
+	response.Success(c, nil, "作业已取消")
+}
+
+// GetMyAssignments 客户查看自己收到的作业
+func (h *AssignmentHandler) GetMyAssignments(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+	status, ok := assignmentStatusQuery(c)
+	if !ok {
+		return
+	}
+	page, pageSize := utils.GetPaginationParams(c)
+
+	assignments, total, err := h.assignmentService.ListForClient(userID, status, page, pageSize)
+	if err != nil {
+		response.InternalError(c, "获取作业失败")
+		return
+	}
+
+	response.Success(c, gin.H{"assignments": assignments, "total": total, "page": page, "page_size": pageSize}, "获取成功")
+}
+
+// CompleteAssignment 客户手动标记作业完成
+func (h *AssignmentHandler) CompleteAssignment(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	assignmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的作业ID")
+		return
+	}
+
+	assignment, err := h.assignmentService.Complete(userID, assignmentID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "作业不存在")
+			return
+		}
+		if errors.Is(err, services.ErrAssignmentNotActive) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.InternalError(c, "完成作业失败")
+		return
+	}
+
+	response.Success(c, assignment, "作业已完成")
+}
@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"strconv"
+
 	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/hub"
+	"fluent-life-backend/internal/models"
 	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
 	"fluent-life-backend/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -10,20 +16,26 @@ import (
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
-	codeService *services.VerificationCodeService
+	authService   *services.AuthService
+	codeService   *services.VerificationCodeService
+	guestService  *services.GuestService
+	changeService *services.IdentifierChangeService
+	roomHub       *hub.RoomHub
 }
 
-func NewAuthHandler(db *gorm.DB, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, cfg *config.Config, roomHub *hub.RoomHub) *AuthHandler {
 	return &AuthHandler{
-		authService: services.NewAuthService(db, cfg),
-		codeService: services.NewVerificationCodeService(db, cfg),
+		authService:   services.NewAuthService(db, cfg),
+		codeService:   services.NewVerificationCodeService(db, cfg),
+		guestService:  services.NewGuestService(db, cfg),
+		changeService: services.NewIdentifierChangeService(db, cfg),
+		roomHub:       roomHub,
 	}
 }
 
 type SendCodeRequest struct {
 	Identifier string `json:"identifier" binding:"required"`
-	Type       string `json:"type" binding:"required,oneof=register login"`
+	Type       string `json:"type" binding:"required,oneof=register login reset_password"`
 }
 
 type RegisterRequest struct {
@@ -31,17 +43,109 @@ type RegisterRequest struct {
 	Identifier string `json:"identifier" binding:"required"`
 	Password   string `json:"password" binding:"required,min=6"`
 	Code       string `json:"code" binding:"required,len=6"`
+	DeviceName string `json:"device_name"`
 }
 
 type LoginRequest struct {
 	Identifier string `json:"identifier" binding:"required"`
 	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name"`
+}
+
+type LoginByCodeRequest struct {
+	Identifier string `json:"identifier" binding:"required"`
+	Code       string `json:"code" binding:"required,len=6"`
+	DeviceName string `json:"device_name"`
+}
+
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // 动态验证码或恢复码
+	DeviceName     string `json:"device_name"`
 }
 
 type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Identifier  string `json:"identifier" binding:"required"`
+	Code        string `json:"code" binding:"required,len=6"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type GuestRequest struct {
+	DeviceName string `json:"device_name"`
+}
+
+type UpgradeGuestRequest struct {
+	Username   string `json:"username"` // 可选，留空保留游客用户名
+	Identifier string `json:"identifier" binding:"required"`
+	Password   string `json:"password" binding:"required,min=6"`
+	Code       string `json:"code" binding:"required,len=6"`
+}
+
+type ChangeIdentifierRequest struct {
+	Identifier string `json:"identifier" binding:"required"`
+}
+
+type ConfirmIdentifierRequest struct {
+	Identifier string `json:"identifier" binding:"required"`
+	Code       string `json:"code" binding:"required,len=6"`
+}
+
+type RevertIdentifierRequest struct {
 	Token string `json:"token" binding:"required"`
 }
 
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// deviceInfo 从请求中提取客户端信息
+func deviceInfo(c *gin.Context, deviceName string) services.DeviceInfo {
+	return services.DeviceInfo{
+		Name:      deviceName,
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+// loginResponse 登录/注册成功后的响应数据
+func loginResponse(user *models.User, tokens *services.TokenPair) gin.H {
+	return gin.H{
+		"user":          user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	}
+}
+
+// challengeResponse 开启两步验证的账号第一步登录成功后的响应
+func challengeResponse(challenge *services.TwoFactorChallenge) gin.H {
+	return gin.H{
+		"two_factor_required": true,
+		"challenge_token":     challenge.ChallengeToken,
+		"expires_in":          challenge.ExpiresIn,
+	}
+}
+
+// respondLoginError 登录被限制时返回 429 和 Retry-After，其余按参数错误返回
+func respondLoginError(c *gin.Context, err error) {
+	var throttled *services.LoginThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.FormatInt(throttled.RetryAfter, 10))
+		response.ErrorWithData(c, 429, throttled.Error(), throttled)
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
+
 func (h *AuthHandler) SendCode(c *gin.Context) {
 	var req SendCodeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -49,7 +153,7 @@ func (h *AuthHandler) SendCode(c *gin.Context) {
 		return
 	}
 
-	if err := h.codeService.SendCode(req.Identifier, req.Type); err != nil {
+	if err := h.codeService.SendCode(req.Identifier, req.Type, c.ClientIP()); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
@@ -64,16 +168,13 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, token, err := h.authService.Register(req.Username, req.Identifier, req.Password, req.Code)
+	user, tokens, err := h.authService.Register(req.Username, req.Identifier, req.Password, req.Code, deviceInfo(c, req.DeviceName))
 	if err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
 
-	response.Success(c, gin.H{
-		"user":  user,
-		"token": token,
-	}, "注册成功")
+	response.Success(c, loginResponse(user, tokens), "注册成功")
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -83,16 +184,61 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, token, err := h.authService.Login(req.Identifier, req.Password)
+	user, tokens, challenge, err := h.authService.Login(req.Identifier, req.Password, deviceInfo(c, req.DeviceName))
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+	if challenge != nil {
+		response.Success(c, challengeResponse(challenge), "请输入两步验证码")
+		return
+	}
+
+	response.Success(c, loginResponse(user, tokens), "登录成功")
+}
+
+// VerifyTwoFactor 登录第二步：提交挑战令牌和动态验证码（或恢复码）
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req VerifyTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	user, tokens, err := h.authService.VerifyTwoFactor(req.ChallengeToken, req.Code, deviceInfo(c, req.DeviceName))
 	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorChallenge) {
+			response.Unauthorized(c, err.Error())
+			return
+		}
+		respondLoginError(c, err)
+		return
+	}
+
+	response.Success(c, loginResponse(user, tokens), "登录成功")
+}
+
+// LoginByCode 验证码登录（未注册的账号可按配置自动注册）
+func (h *AuthHandler) LoginByCode(c *gin.Context) {
+	var req LoginByCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
 
-	response.Success(c, gin.H{
-		"user":  user,
-		"token": token,
-	}, "登录成功")
+	user, tokens, challenge, created, err := h.authService.LoginByCode(req.Identifier, req.Code, deviceInfo(c, req.DeviceName))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if challenge != nil {
+		response.Success(c, challengeResponse(challenge), "请输入两步验证码")
+		return
+	}
+
+	data := loginResponse(user, tokens)
+	data["is_new_user"] = created
+	response.Success(c, data, "登录成功")
 }
 
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
@@ -102,12 +248,219 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// TODO: 实现Token刷新逻辑
-	response.Success(c, gin.H{"token": req.Token}, "Token刷新成功")
+	tokens, err := h.authService.RefreshToken(req.RefreshToken, deviceInfo(c, ""))
+	if err != nil {
+		if errors.Is(err, services.ErrRefreshTokenInvalid) || errors.Is(err, services.ErrRefreshTokenReused) {
+			response.Unauthorized(c, err.Error())
+			return
+		}
+		response.InternalError(c, "Token刷新失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	}, "Token刷新成功")
 }
 
+// Logout 退出当前登录（吊销刷新令牌所在的会话）
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
 
+	session, err := h.authService.Logout(req.RefreshToken, deviceInfo(c, ""))
+	if err != nil && err != services.ErrRefreshTokenInvalid {
+		response.InternalError(c, "退出登录失败")
+		return
+	}
+	if session != nil && h.roomHub != nil {
+		h.roomHub.DisconnectSession(session.ID.String())
+	}
 
+	response.Success(c, nil, "已退出登录")
+}
 
+// LogoutAll 退出所有设备上的登录
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
 
+	if err := h.authService.LogoutAll(userID, deviceInfo(c, "")); err != nil {
+		response.InternalError(c, "退出登录失败")
+		return
+	}
+	if h.roomHub != nil {
+		h.roomHub.DisconnectUser(userID.String())
+	}
+
+	response.Success(c, nil, "已退出所有设备")
+}
 
+// ResetPassword 通过验证码重置密码（忘记密码）
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	user, err := h.authService.ResetPassword(req.Identifier, req.Code, req.NewPassword, deviceInfo(c, ""))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if h.roomHub != nil {
+		h.roomHub.DisconnectUser(user.ID.String())
+	}
+
+	response.Success(c, nil, "密码已重置，请重新登录")
+}
+
+// CreateGuest 创建游客账号，可记录训练但不能参与社区互动
+func (h *AuthHandler) CreateGuest(c *gin.Context) {
+	var req GuestRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	user, tokens, err := h.guestService.Create(deviceInfo(c, req.DeviceName))
+	if err != nil {
+		if errors.Is(err, services.ErrGuestLimitExceeded) {
+			response.Error(c, 429, err.Error())
+			return
+		}
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, loginResponse(user, tokens), "已进入游客模式")
+}
+
+// UpgradeGuest 游客账号绑定邮箱/手机号和密码，升级为正式账号
+func (h *AuthHandler) UpgradeGuest(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req UpgradeGuestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	user, err := h.guestService.Upgrade(userID, req.Username, req.Identifier, req.Password, req.Code, deviceInfo(c, ""))
+	if err != nil {
+		if errors.Is(err, services.ErrNotGuest) {
+			response.Forbidden(c, err.Error())
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, user, "账号升级成功")
+}
+
+// RequestIdentifierChange 向新的邮箱/手机号发送更换绑定的验证码
+func (h *AuthHandler) RequestIdentifierChange(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req ChangeIdentifierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.changeService.RequestChange(userID, req.Identifier, c.ClientIP()); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, nil, "验证码已发送")
+}
+
+// ConfirmIdentifierChange 校验验证码后更换绑定的邮箱/手机号，原邮箱/手机号会收到撤销通知
+func (h *AuthHandler) ConfirmIdentifierChange(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req ConfirmIdentifierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	user, err := h.changeService.ConfirmChange(userID, req.Identifier, req.Code, deviceInfo(c, ""))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, user, "绑定已更换")
+}
+
+// RevertIdentifierChange 原邮箱/手机号凭撤销码恢复绑定，该账号所有设备将被退出登录
+func (h *AuthHandler) RevertIdentifierChange(c *gin.Context) {
+	var req RevertIdentifierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	userID, err := h.changeService.Revert(req.Token, deviceInfo(c, ""))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if h.roomHub != nil {
+		h.roomHub.DisconnectUser(userID.String())
+	}
+
+	response.Success(c, nil, "已恢复原绑定，请重新登录")
+}
+
+// ChangePassword 修改密码（需要原密码），其他设备将被退出登录
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+	sessionID, _ := utils.GetSessionID(c)
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.authService.ChangePassword(userID, sessionID, req.OldPassword, req.NewPassword, deviceInfo(c, "")); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if h.roomHub != nil {
+		h.roomHub.DisconnectOtherSessions(userID.String(), sessionID.String())
+	}
+
+	response.Success(c, nil, "密码修改成功")
+}
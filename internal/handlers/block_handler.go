@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BlockHandler struct {
+	blockService *services.BlockService
+}
+
+func NewBlockHandler(db *gorm.DB) *BlockHandler {
+	return &BlockHandler{
+		blockService: services.NewBlockService(db),
+	}
+}
+
+// GetBlocks 获取自己拉黑（默认）或静音（?kind=mute）的用户列表
+func (h *BlockHandler) GetBlocks(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	kind := c.DefaultQuery("kind", models.BlockKindBlock)
+	if !models.IsValidBlockKind(kind) {
+		response.BadRequest(c, "无效的类型")
+		return
+	}
+	page, pageSize := utils.GetPaginationParams(c)
+
+	blocks, total, err := h.blockService.List(userID, kind, page, pageSize)
+	if err != nil {
+		response.InternalError(c, "获取列表失败")
+		return
+	}
+
+	response.Success(c, gin.H{"blocks": blocks, "total": total, "page": page, "page_size": pageSize}, "获取成功")
+}
+
+// BlockUser 拉黑用户
+func (h *BlockHandler) BlockUser(c *gin.Context) {
+	h.block(c, models.BlockKindBlock, "已拉黑")
+}
+
+// MuteUser 静音用户
+func (h *BlockHandler) MuteUser(c *gin.Context) {
+	h.block(c, models.BlockKindMute, "已静音")
+}
+
+// UnblockUser 解除拉黑
+func (h *BlockHandler) UnblockUser(c *gin.Context) {
+	h.unblock(c, models.BlockKindBlock, "已解除拉黑")
+}
+
+// UnmuteUser 解除静音
+func (h *BlockHandler) UnmuteUser(c *gin.Context) {
+	h.unblock(c, models.BlockKindMute, "已解除静音")
+}
+
+func (h *BlockHandler) block(c *gin.Context, kind, message string) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	block, err := h.blockService.Block(userID, targetID, kind)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCannotBlockSelf):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.NotFound(c, "用户不存在")
+		default:
+			response.InternalError(c, "操作失败")
+		}
+		return
+	}
+
+	response.Success(c, block, message)
+}
+
+func (h *BlockHandler) unblock(c *gin.Context, kind, message string) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	if err := h.blockService.Unblock(userID, targetID, kind); err != nil {
+		response.InternalError(c, "操作失败")
+		return
+	}
+
+	response.Success(c, nil, message)
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ClinicalNoteHandler struct {
+	noteService *services.ClinicalNoteService
+}
+
+func NewClinicalNoteHandler(db *gorm.DB) *ClinicalNoteHandler {
+	return &ClinicalNoteHandler{
+		noteService: services.NewClinicalNoteService(db),
+	}
+}
+
+type CreateClinicalNoteRequest struct {
+	SessionDate       string      `json:"session_date" binding:"required"` // YYYY-MM-DD
+	Content           string      `json:"content" binding:"required"`
+	Tags              []string    `json:"tags"`
+	TrainingRecordIDs []uuid.UUID `json:"training_record_ids"`
+	SpeechAnalysis    string      `json:"speech_analysis"`
+	SharedWithClient  bool        `json:"shared_with_client"`
+}
+
+type UpdateClinicalNoteRequest struct {
+	Version           int          `json:"version"` // 当前版本号，传入时用于检测并发修改
+	SessionDate       *string      `json:"session_date"`
+	Content           *string      `json:"content"`
+	Tags              *[]string    `json:"tags"`
+	TrainingRecordIDs *[]uuid.UUID `json:"training_record_ids"`
+	SpeechAnalysis    *string      `json:"speech_analysis"`
+	SharedWithClient  *bool        `json:"shared_with_client"`
+}
+
+// respondNoteError 将临床记录相关错误映射为响应
+func respondNoteError(c *gin.Context, err error, fallback string) {
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		response.NotFound(c, "记录不存在")
+	case errors.Is(err, services.ErrNoteVersionConflict):
+		response.Error(c, 409, err.Error())
+	case errors.Is(err, services.ErrClientNotLinked), errors.Is(err, services.ErrConsentNotGranted):
+		respondClientError(c, err, fallback)
+	default:
+		response.BadRequest(c, err.Error())
+	}
+}
+
+// noteIDParam 解析当前治疗师和路径中的记录ID
+func noteIDParam(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return uuid.Nil, uuid.Nil, false
+	}
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的记录ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, noteID, true
+}
+
+// CreateNote 治疗师为客户撰写临床记录
+func (h *ClinicalNoteHandler) CreateNote(c *gin.Context) {
+	therapistID, clientID, ok := clientParams(c)
+	if !ok {
+		return
+	}
+
+	var req CreateClinicalNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	sessionDate, err := time.Parse("2006-01-02", req.SessionDate)
+	if err != nil {
+		response.BadRequest(c, "日期格式应为 YYYY-MM-DD")
+		return
+	}
+
+	note, err := h.noteService.Create(therapistID, clientID, services.ClinicalNoteInput{
+		SessionDate:       sessionDate,
+		Content:           req.Content,
+		Tags:              req.Tags,
+		TrainingRecordIDs: req.TrainingRecordIDs,
+		SpeechAnalysis:    req.SpeechAnalysis,
+		SharedWithClient:  req.SharedWithClient,
+	})
+	if err != nil {
+		respondNoteError(c, err, "创建记录失败")
+		return
+	}
+
+	response.Success(c, note, "记录已保存")
+}
+
+// GetClientNotes 治疗师为某个客户撰写的记录（?tag= 按标签过滤）
+func (h *ClinicalNoteHandler) GetClientNotes(c *gin.Context) {
+	therapistID, clientID, ok := clientParams(c)
+	if !ok {
+		return
+	}
+	page, pageSize := utils.GetPaginationParams(c)
+
+	notes, total, err := h.noteService.ListForTherapist(therapistID, clientID, c.Query("tag"), page, pageSize)
+	if err != nil {
+		response.InternalError(c, "获取记录失败")
+		return
+	}
+
+	response.Success(c, gin.H{"notes": notes, "total": total, "page": page, "page_size": pageSize}, "获取成功")
+}
+
+// GetNote 治疗师查看一条记录
+func (h *ClinicalNoteHandler) GetNote(c *gin.Context) {
+	therapistID, noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+
+	note, err := h.noteService.Get(therapistID, noteID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "记录不存在")
+			return
+		}
+		response.InternalError(c, "获取记录失败")
+		return
+	}
+
+	response.Success(c, note, "获取成功")
+}
+
+// UpdateNote 治疗师修改记录，旧内容保存在历史版本中
+func (h *ClinicalNoteHandler) UpdateNote(c *gin.Context) {
+	therapistID, noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateClinicalNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	update := services.ClinicalNoteUpdate{
+		Version:           req.Version,
+		Content:           req.Content,
+		Tags:              req.Tags,
+		TrainingRecordIDs: req.TrainingRecordIDs,
+		SpeechAnalysis:    req.SpeechAnalysis,
+		SharedWithClient:  req.SharedWithClient,
+	}
+	if req.SessionDate != nil {
+		sessionDate, err := time.Parse("2006-01-02", *req.SessionDate)
+		if err != nil {
+			response.BadRequest(c, "日期格式应为 YYYY-MM-DD")
+			return
+		}
+		update.SessionDate = &sessionDate
+	}
+
+	note, err := h.noteService.Update(therapistID, noteID, update)
+	if err != nil {
+		respondNoteError(c, err, "修改记录失败")
+		return
+	}
+
+	response.Success(c, note, "记录已更新")
+}
+
+// DeleteNote 治疗师删除记录（历史版本保留）
+func (h *ClinicalNoteHandler) DeleteNote(c *gin.Context) {
+	therapistID, noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.noteService.Delete(therapistID, noteID); err != nil {
+		respondNoteError(c, err, "删除记录失败")
+		return
+	}
+
+	response.Success(c, nil, "记录已删除")
+}
+
+// GetNoteVersions 治疗师查看记录的全部历史版本
+func (h *ClinicalNoteHandler) GetNoteVersions(c *gin.Context) {
+	therapistID, noteID, ok := noteIDParam(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.noteService.Versions(therapistID, noteID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "记录不存在")
+			return
+		}
+		response.InternalError(c, "获取历史版本失败")
+		return
+	}
+
+	response.Success(c, gin.H{"versions": versions}, "获取成功")
+}
+
+// GetSharedNotes 客户查看治疗师分享给自己的记录
+func (h *ClinicalNoteHandler) GetSharedNotes(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+	page, pageSize := utils.GetPaginationParams(c)
+
+	notes, total, err := h.noteService.ListForClient(userID, page, pageSize)
+	if err != nil {
+		response.InternalError(c, "获取记录失败")
+		return
+	}
+
+	response.Success(c, gin.H{"notes": notes, "total": total, "page": page, "page_size": pageSize}, "获取成功")
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"os"
 	"strconv"
 
@@ -18,6 +19,7 @@ type CommunityHandler struct {
 	communityService *services.CommunityService
 	followService    *services.FollowService
 	collectionService *services.CollectionService
+	auditService     *services.AuditService
 }
 
 func NewCommunityHandler(db *gorm.DB) *CommunityHandler {
@@ -26,6 +28,7 @@ func NewCommunityHandler(db *gorm.DB) *CommunityHandler {
 		communityService: services.NewCommunityService(db),
 		followService:    services.NewFollowService(db),
 		collectionService: services.NewCollectionService(db),
+		auditService:     services.NewAuditService(db),
 	}
 }
 
@@ -148,6 +151,10 @@ func (h *CommunityHandler) GetUserPosts(c *gin.Context) {
 	}
 
 	posts, total, err := h.communityService.GetUserPosts(targetUserID, page, pageSize, currentUserID)
+	if errors.Is(err, services.ErrProfileHidden) {
+		response.Forbidden(c, err.Error())
+		return
+	}
 	if err != nil {
 		response.InternalError(c, "获取失败")
 		return
@@ -187,7 +194,12 @@ func (h *CommunityHandler) GetPost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.communityService.GetPost(postID)
+	var viewerID *uuid.UUID
+	if uid, ok := utils.GetUserID(c); ok {
+		viewerID = &uid
+	}
+
+	post, err := h.communityService.GetPost(postID, viewerID)
 	if err != nil {
 		response.NotFound(c, "帖子不存在")
 		return
@@ -225,7 +237,12 @@ func (h *CommunityHandler) GetComments(c *gin.Context) {
 		return
 	}
 
-	comments, err := h.communityService.GetComments(postID)
+	var viewerID *uuid.UUID
+	if uid, ok := utils.GetUserID(c); ok {
+		viewerID = &uid
+	}
+
+	comments, err := h.communityService.GetComments(postID, viewerID)
 	if err != nil {
 		response.InternalError(c, "获取失败")
 		return
@@ -258,6 +275,14 @@ func (h *CommunityHandler) CreateComment(c *gin.Context) {
 	}
 
 	comment, err := h.communityService.CreateComment(userID, postID, req.Content)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.NotFound(c, "帖子不存在")
+		return
+	}
+	if errors.Is(err, services.ErrCommentNotAllowed) {
+		response.Forbidden(c, err.Error())
+		return
+	}
 	if err != nil {
 		response.InternalError(c, "评论失败")
 		return
@@ -289,6 +314,12 @@ func (h *CommunityHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(deviceInfo(c, ""), models.AuditEvent{
+		ActorID:    &userID,
+		Action:     models.AuditPostDelete,
+		TargetType: models.AuditTargetPost,
+		TargetID:   postID.String(),
+	})
 	response.Success(c, nil, "删除成功")
 }
 
@@ -315,6 +346,12 @@ func (h *CommunityHandler) DeleteComment(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(deviceInfo(c, ""), models.AuditEvent{
+		ActorID:    &userID,
+		Action:     models.AuditCommentDelete,
+		TargetType: models.AuditTargetComment,
+		TargetID:   commentID.String(),
+	})
 	response.Success(c, nil, "删除成功")
 }
 
@@ -43,6 +43,10 @@ func (h *FollowHandler) FollowUser(c *gin.Context) {
 			response.BadRequest(c, "不能关注自己")
 			return
 		}
+		if err == services.ErrUserBlocked {
+			response.Forbidden(c, err.Error())
+			return
+		}
 		response.InternalError(c, "关注失败")
 		return
 	}
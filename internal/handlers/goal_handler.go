@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type GoalHandler struct {
+	goalService *services.GoalService
+}
+
+func NewGoalHandler(db *gorm.DB) *GoalHandler {
+	return &GoalHandler{
+		goalService: services.NewGoalService(db),
+	}
+}
+
+type CreateGoalRequest struct {
+	Period string `json:"period" binding:"required,oneof=daily weekly"`
+	Metric string `json:"metric" binding:"required,oneof=minutes sessions"`
+	Type   string `json:"type" binding:"omitempty,oneof=meditation airflow exposure practice"` // 留空统计全部训练类型
+	Target int    `json:"target" binding:"required,min=1,max=10000"`
+}
+
+type UpdateGoalRequest struct {
+	Target *int  `json:"target" binding:"omitempty,min=1,max=10000"`
+	Active *bool `json:"active"`
+}
+
+// goalParams 解析当前用户和路径中的目标ID
+func goalParams(c *gin.Context) (userID, goalID uuid.UUID, ok bool) {
+	userID, ok = utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+	goalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的目标ID")
+		return userID, goalID, false
+	}
+	return userID, goalID, true
+}
+
+// CreateGoal 创建每日/每周练习目标
+func (h *GoalHandler) CreateGoal(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req CreateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	goal, err := h.goalService.Create(userID, services.GoalInput{
+		Period: req.Period,
+		Metric: req.Metric,
+		Type:   req.Type,
+		Target: req.Target,
+	})
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, goal, "目标已创建")
+}
+
+// GetGoals 全部目标及当前周期的进度
+func (h *GoalHandler) GetGoals(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	goals, err := h.goalService.List(userID)
+	if err != nil {
+		response.InternalError(c, "获取目标失败")
+		return
+	}
+
+	response.Success(c, gin.H{"goals": goals}, "获取成功")
+}
+
+// GetGoal 单个目标及当前周期的进度
+func (h *GoalHandler) GetGoal(c *gin.Context) {
+	userID, goalID, ok := goalParams(c)
+	if !ok {
+		return
+	}
+
+	goal, err := h.goalService.Get(userID, goalID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "目标不存在")
+			return
+		}
+		response.InternalError(c, "获取目标失败")
+		return
+	}
+
+	response.Success(c, goal, "获取成功")
+}
+
+// UpdateGoal 修改目标值或暂停/恢复目标
+func (h *GoalHandler) UpdateGoal(c *gin.Context) {
+	userID, goalID, ok := goalParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	goal, err := h.goalService.Update(userID, goalID, services.GoalUpdate{Target: req.Target, Active: req.Active})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "目标不存在")
+			return
+		}
+		response.InternalError(c, "修改目标失败")
+		return
+	}
+
+	response.Success(c, goal, "目标已更新")
+}
+
+// DeleteGoal 删除目标及其历史
+func (h *GoalHandler) DeleteGoal(c *gin.Context) {
+	userID, goalID, ok := goalParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.goalService.Delete(userID, goalID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "目标不存在")
+			return
+		}
+		response.InternalError(c, "删除目标失败")
+		return
+	}
+
+	response.Success(c, nil, "目标已删除")
+}
+
+// GetGoalHistory 目标每个周期的达成情况
+func (h *GoalHandler) GetGoalHistory(c *gin.Context) {
+	userID, goalID, ok := goalParams(c)
+	if !ok {
+		return
+	}
+	page, pageSize := utils.GetPaginationParams(c)
+
+	periods, total, err := h.goalService.History(userID, goalID, page, pageSize)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "目标不存在")
+			return
+		}
+		response.InternalError(c, "获取目标历史失败")
+		return
+	}
+
+	response.Success(c, gin.H{"periods": periods, "total": total, "page": page, "page_size": pageSize}, "获取成功")
+}
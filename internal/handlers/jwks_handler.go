@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"fluent-life-backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	cfg *config.Config
+}
+
+func NewJWKSHandler(cfg *config.Config) *JWKSHandler {
+	return &JWKSHandler{cfg: cfg}
+}
+
+// GetJWKS 发布访问令牌的验证公钥，供其他服务按 kid 校验令牌。
+// 按 RFC 7517 直接返回 JWK Set，不使用统一响应格式
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.cfg.JWTKeySet.JWKS())
+}
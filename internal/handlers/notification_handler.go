@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(db *gorm.DB) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: services.NewNotificationService(db),
+	}
+}
+
+// GetNotifications 获取站内通知（?unread=true 只看未读）
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+	page, pageSize := utils.GetPaginationParams(c)
+
+	notifications, total, unread, err := h.notificationService.List(userID, c.Query("unread") == "true", page, pageSize)
+	if err != nil {
+		response.InternalError(c, "获取通知失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"unread_count":  unread,
+		"page":          page,
+		"page_size":     pageSize,
+	}, "获取成功")
+}
+
+// MarkRead 将一条通知标记为已读
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的通知ID")
+		return
+	}
+
+	if err := h.notificationService.MarkRead(userID, notificationID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "通知不存在")
+			return
+		}
+		response.InternalError(c, "标记已读失败")
+		return
+	}
+
+	response.Success(c, nil, "已标记为已读")
+}
+
+// MarkAllRead 将全部通知标记为已读
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	if err := h.notificationService.MarkAllRead(userID); err != nil {
+		response.InternalError(c, "标记已读失败")
+		return
+	}
+
+	response.Success(c, nil, "已全部标记为已读")
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OIDCHandler struct {
+	oidcService *services.OIDCService
+}
+
+func NewOIDCHandler(db *gorm.DB, cfg *config.Config) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: services.NewOIDCService(db, cfg),
+	}
+}
+
+type OIDCCallbackRequest struct {
+	Code       string `json:"code" form:"code"`
+	State      string `json:"state" form:"state"`
+	Error      string `json:"error" form:"error"`
+	DeviceName string `json:"device_name" form:"device_name"`
+}
+
+// GetProviders 获取可用的第三方登录方式
+func (h *OIDCHandler) GetProviders(c *gin.Context) {
+	response.Success(c, gin.H{"providers": h.oidcService.Providers()}, "获取成功")
+}
+
+// Authorize 发起第三方登录；?redirect=true 时直接 302 跳转到授权页
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	authURL, state, err := h.oidcService.BeginLogin(c.Param("provider"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if c.Query("redirect") == "true" {
+		c.Redirect(http.StatusFound, authURL)
+		return
+	}
+	response.Success(c, gin.H{"authorization_url": authURL, "state": state}, "获取成功")
+}
+
+// Callback 授权回调，支持提供方直接重定向（GET 查询参数）或客户端转交（POST JSON）
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req OIDCCallbackRequest
+	if err := c.ShouldBind(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if req.Error != "" {
+		response.BadRequest(c, "第三方授权被拒绝: "+req.Error)
+		return
+	}
+	if req.Code == "" || req.State == "" {
+		response.BadRequest(c, "缺少 code 或 state")
+		return
+	}
+
+	result, err := h.oidcService.HandleCallback(c.Param("provider"), req.State, req.Code, deviceInfo(c, req.DeviceName))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if result.Identity != nil {
+		response.Success(c, result.Identity, "绑定成功")
+		return
+	}
+
+	if result.Challenge != nil {
+		response.Success(c, challengeResponse(result.Challenge), "请输入两步验证码")
+		return
+	}
+
+	data := loginResponse(result.User, result.Tokens)
+	data["is_new_user"] = result.Created
+	response.Success(c, data, "登录成功")
+}
+
+// LinkIdentity 已登录用户发起第三方账号绑定，回调同样走 /auth/oidc/:provider/callback
+func (h *OIDCHandler) LinkIdentity(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	authURL, state, err := h.oidcService.BeginLink(userID, c.Param("provider"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"authorization_url": authURL, "state": state}, "获取成功")
+}
+
+// GetIdentities 获取已绑定的第三方账号
+func (h *OIDCHandler) GetIdentities(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	identities, err := h.oidcService.ListIdentities(userID)
+	if err != nil {
+		response.InternalError(c, "获取绑定信息失败")
+		return
+	}
+	response.Success(c, identities, "获取成功")
+}
+
+// UnlinkIdentity 解绑第三方账号
+func (h *OIDCHandler) UnlinkIdentity(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	if err := h.oidcService.Unlink(userID, c.Param("provider")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Success(c, nil, "解绑成功")
+}
+
+func (h *OIDCHandler) handleError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrOIDCProviderNotFound, services.ErrIdentityNotLinked:
+		response.NotFound(c, err.Error())
+	case services.ErrIdentityAlreadyLinked, services.ErrOIDCEmailInUse:
+		response.Error(c, 409, err.Error())
+	case services.ErrUserSuspended:
+		response.Forbidden(c, err.Error())
+	case gorm.ErrRecordNotFound:
+		response.NotFound(c, "用户不存在")
+	default:
+		response.BadRequest(c, err.Error())
+	}
+}
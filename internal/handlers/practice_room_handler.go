@@ -160,6 +160,10 @@ func (h *PracticeRoomHandler) JoinRoom(c *gin.Context) {
 			response.NotFound(c, "房间不存在或已满")
 			return
 		}
+		if err == services.ErrBlockedByHost {
+			response.Forbidden(c, err.Error())
+			return
+		}
 		response.InternalError(c, "加入房间失败")
 		return
 	}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PrivacyHandler struct {
+	privacyService *services.PrivacyService
+}
+
+func NewPrivacyHandler(db *gorm.DB) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: services.NewPrivacyService(db),
+	}
+}
+
+// GetSettings 获取当前用户的隐私设置
+func (h *PrivacyHandler) GetSettings(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	settings, err := h.privacyService.Get(userID)
+	if err != nil {
+		response.InternalError(c, "获取隐私设置失败")
+		return
+	}
+
+	response.Success(c, settings, "获取成功")
+}
+
+// UpdateSettings 修改隐私设置，只更新请求中出现的字段
+func (h *PrivacyHandler) UpdateSettings(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req services.PrivacyUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	settings, err := h.privacyService.Update(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPrivacySettings) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.InternalError(c, "更新隐私设置失败")
+		return
+	}
+
+	response.Success(c, settings, "更新成功")
+}
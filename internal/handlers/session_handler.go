@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/hub"
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+	roomHub        *hub.RoomHub
+}
+
+func NewSessionHandler(db *gorm.DB, cfg *config.Config, roomHub *hub.RoomHub) *SessionHandler {
+	return &SessionHandler{
+		sessionService: services.NewSessionService(db, cfg),
+		roomHub:        roomHub,
+	}
+}
+
+// GetSessions 获取当前用户的登录设备列表
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+	currentSessionID, _ := utils.GetSessionID(c)
+
+	sessions, err := h.sessionService.GetActiveSessions(userID, currentSessionID)
+	if err != nil {
+		response.InternalError(c, "获取登录设备失败")
+		return
+	}
+
+	response.Success(c, gin.H{"sessions": sessions}, "获取成功")
+}
+
+// RevokeSession 结束指定设备上的登录，并断开该会话的实时连接
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的会话ID")
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(userID, sessionID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "会话不存在或已失效")
+			return
+		}
+		response.InternalError(c, "结束会话失败")
+		return
+	}
+
+	if h.roomHub != nil {
+		h.roomHub.DisconnectSession(sessionID.String())
+	}
+
+	response.Success(c, nil, "已结束该设备的登录")
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"errors"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TherapistHandler struct {
+	therapistService *services.TherapistService
+}
+
+func NewTherapistHandler(db *gorm.DB, cfg *config.Config) *TherapistHandler {
+	return &TherapistHandler{
+		therapistService: services.NewTherapistService(db, cfg),
+	}
+}
+
+type CreateInviteRequest struct {
+	Scopes []string `json:"scopes"` // 留空申请全部范围
+	Note   string   `json:"note"`
+}
+
+type AcceptInviteRequest struct {
+	Token  string   `json:"token" binding:"required"`
+	Scopes []string `json:"scopes"` // 不传表示同意治疗师申请的全部范围
+}
+
+type UpdateConsentRequest struct {
+	Scopes []string `json:"scopes" binding:"required"` // 空数组表示不授权查看任何数据
+}
+
+// respondClientError 将客户数据访问错误映射为响应
+func respondClientError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrClientNotLinked):
+		response.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrConsentNotGranted):
+		response.Forbidden(c, err.Error())
+	default:
+		response.InternalError(c, fallback)
+	}
+}
+
+// CreateInvite 治疗师创建客户邀请，邀请码只返回一次
+func (h *TherapistHandler) CreateInvite(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req CreateInviteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	invite, token, err := h.therapistService.CreateInvite(userID, req.Scopes, req.Note)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"token": token, "invite": invite}, "邀请已创建，邀请码只显示这一次")
+}
+
+// GetInvites 治疗师尚未被接受的邀请
+func (h *TherapistHandler) GetInvites(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	invites, err := h.therapistService.ListInvites(userID)
+	if err != nil {
+		response.InternalError(c, "获取邀请失败")
+		return
+	}
+
+	response.Success(c, gin.H{"invites": invites}, "获取成功")
+}
+
+// CancelInvite 治疗师取消邀请
+func (h *TherapistHandler) CancelInvite(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	inviteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的邀请ID")
+		return
+	}
+
+	if err := h.therapistService.CancelInvite(userID, inviteID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "邀请不存在或已被接受")
+			return
+		}
+		response.InternalError(c, "取消邀请失败")
+		return
+	}
+
+	response.Success(c, nil, "邀请已取消")
+}
+
+// GetClients 治疗师的客户列表及训练统计
+func (h *TherapistHandler) GetClients(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	clients, err := h.therapistService.ListClients(userID)
+	if err != nil {
+		response.InternalError(c, "获取客户列表失败")
+		return
+	}
+
+	response.Success(c, gin.H{"clients": clients}, "获取成功")
+}
+
+// clientParams 解析当前治疗师和路径中的客户ID
+func clientParams(c *gin.Context) (therapistID, clientID uuid.UUID, ok bool) {
+	therapistID, ok = utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+	clientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return therapistID, clientID, false
+	}
+	return therapistID, clientID, true
+}
+
+// GetClient 单个客户的概况
+func (h *TherapistHandler) GetClient(c *gin.Context) {
+	therapistID, clientID, ok := clientParams(c)
+	if !ok {
+		return
+	}
+
+	client, err := h.therapistService.GetClient(therapistID, clientID)
+	if err != nil {
+		respondClientError(c, err, "获取客户信息失败")
+		return
+	}
+
+	response.Success(c, client, "获取成功")
+}
+
+// GetClientRecords 客户的训练记录
+func (h *TherapistHandler) GetClientRecords(c *gin.Context) {
+	therapistID, clientID, ok := clientParams(c)
+	if !ok {
+		return
+	}
+	page, pageSize := utils.GetPaginationParams(c)
+
+	records, total, err := h.therapistService.GetClientRecords(therapistID, clientID, page, pageSize, c.Query("type"))
+	if err != nil {
+		respondClientError(c, err, "获取记录失败")
+		return
+	}
+
+	response.Success(c, gin.H{"records": records, "total": total, "page": page, "page_size": pageSize}, "获取成功")
+}
+
+// GetClientMeditationProgress 客户的冥想进度
+func (h *TherapistHandler) GetClientMeditationProgress(c *gin.Context) {
+	therapistID, clientID, ok := clientParams(c)
+	if !ok {
+		return
+	}
+
+	progress, err := h.therapistService.GetClientMeditationProgress(therapistID, clientID)
+	if err != nil {
+		respondClientError(c, err, "获取冥想进度失败")
+		return
+	}
+
+	response.Success(c, progress, "获取成功")
+}
+
+// GetClientAIConversation 客户与 AI 导师的对话
+func (h *TherapistHandler) GetClientAIConversation(c *gin.Context) {
+	therapistID, clientID, ok := clientParams(c)
+	if !ok {
+		return
+	}
+
+	conversation, err := h.therapistService.GetClientAIConversation(therapistID, clientID)
+	if err != nil {
+		respondClientError(c, err, "获取对话失败")
+		return
+	}
+
+	response.Success(c, conversation, "获取成功")
+}
+
+// PreviewInvite 客户查看邀请详情（治疗师和申请的数据范围）
+func (h *TherapistHandler) PreviewInvite(c *gin.Context) {
+	invite, err := h.therapistService.PreviewInvite(c.Query("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrInviteInvalid) {
+			response.NotFound(c, err.Error())
+			return
+		}
+		response.InternalError(c, "获取邀请失败")
+		return
+	}
+
+	response.Success(c, gin.H{"invite": invite, "available_scopes": models.ConsentScopes}, "获取成功")
+}
+
+// AcceptInvite 客户接受邀请并选择授权范围
+func (h *TherapistHandler) AcceptInvite(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req AcceptInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	link, err := h.therapistService.AcceptInvite(userID, req.Token, req.Scopes, deviceInfo(c, ""))
+	if err != nil {
+		if errors.Is(err, services.ErrInviteInvalid) {
+			response.NotFound(c, err.Error())
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, link, "已与治疗师建立关联")
+}
+
+// GetTherapists 客户当前关联的治疗师及授权范围
+func (h *TherapistHandler) GetTherapists(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	links, err := h.therapistService.ListTherapists(userID)
+	if err != nil {
+		response.InternalError(c, "获取治疗师列表失败")
+		return
+	}
+
+	response.Success(c, gin.H{"therapists": links, "available_scopes": models.ConsentScopes}, "获取成功")
+}
+
+// UpdateConsent 客户修改对治疗师的授权范围
+func (h *TherapistHandler) UpdateConsent(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	linkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的关联ID")
+		return
+	}
+
+	var req UpdateConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	link, err := h.therapistService.UpdateConsent(userID, linkID, req.Scopes, deviceInfo(c, ""))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "关联不存在")
+			return
+		}
+		if errors.Is(err, services.ErrInvalidConsentScope) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.InternalError(c, "修改授权失败")
+		return
+	}
+
+	response.Success(c, link, "授权已更新")
+}
+
+// RevokeTherapist 客户撤销对治疗师的授权
+func (h *TherapistHandler) RevokeTherapist(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	linkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "无效的关联ID")
+		return
+	}
+
+	if err := h.therapistService.Revoke(userID, linkID, deviceInfo(c, "")); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "关联不存在")
+			return
+		}
+		response.InternalError(c, "撤销授权失败")
+		return
+	}
+
+	response.Success(c, nil, "已撤销授权")
+}
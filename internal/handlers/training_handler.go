@@ -124,6 +124,23 @@ func (h *TrainingHandler) GetMeditationProgress(c *gin.Context) {
 	response.Success(c, progress, "获取成功")
 }
 
+// GetStreak 连续练习天数、冻结次数和下一个里程碑
+func (h *TrainingHandler) GetStreak(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	streak, err := h.trainingService.GetStreak(userID)
+	if err != nil {
+		response.InternalError(c, "获取连续练习天数失败")
+		return
+	}
+
+	response.Success(c, streak, "获取成功")
+}
+
 func (h *TrainingHandler) GetWeeklyStats(c *gin.Context) {
 	userID, ok := utils.GetUserID(c)
 	if !ok {
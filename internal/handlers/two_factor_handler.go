@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/services"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *services.TwoFactorService
+}
+
+func NewTwoFactorHandler(db *gorm.DB, cfg *config.Config) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: services.NewTwoFactorService(db, cfg),
+	}
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+// GetStatus 获取两步验证状态
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	status, err := h.twoFactorService.Status(userID)
+	if err != nil {
+		response.InternalError(c, "获取两步验证状态失败")
+		return
+	}
+
+	response.Success(c, status, "获取成功")
+}
+
+// Enroll 生成 TOTP 密钥和 otpauth:// 地址，确认前不生效
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	enrollment, err := h.twoFactorService.BeginEnrollment(userID)
+	if err != nil {
+		h.handleError(c, err, "生成两步验证密钥失败")
+		return
+	}
+
+	response.Success(c, enrollment, "请使用验证器 App 扫码并输入验证码确认")
+}
+
+// Confirm 提交验证器上的验证码，开启两步验证并返回恢复码
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	codes, err := h.twoFactorService.ConfirmEnrollment(userID, req.Code)
+	if err != nil {
+		h.handleError(c, err, "开启两步验证失败")
+		return
+	}
+
+	response.Success(c, gin.H{"recovery_codes": codes}, "两步验证已开启，请妥善保存恢复码")
+}
+
+// Disable 关闭两步验证，需要当前的动态验证码
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.twoFactorService.Disable(userID, req.Code, c.ClientIP()); err != nil {
+		h.handleError(c, err, "关闭两步验证失败")
+		return
+	}
+
+	response.Success(c, nil, "两步验证已关闭")
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		response.Unauthorized(c, "未找到用户信息")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(userID, req.Code, c.ClientIP())
+	if err != nil {
+		h.handleError(c, err, "生成恢复码失败")
+		return
+	}
+
+	response.Success(c, gin.H{"recovery_codes": codes}, "已重新生成恢复码，请妥善保存")
+}
+
+func (h *TwoFactorHandler) handleError(c *gin.Context, err error, fallback string) {
+	var throttled *services.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		respondLoginError(c, err)
+	case errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnrolling),
+		errors.Is(err, services.ErrTwoFactorCodeInvalid):
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.NotFound(c, "用户不存在")
+	default:
+		utils.APILog("[TwoFactorHandler] ❌ %s: %v", fallback, err)
+		response.InternalError(c, fallback)
+	}
+}
@@ -77,6 +77,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	var req struct {
 		Username *string `json:"username"`
 		AvatarURL *string `json:"avatar_url"`
+		Timezone *string `json:"timezone"` // IANA 时区，空字符串表示恢复默认并重新按客户端自动识别
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
@@ -95,6 +96,13 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	if req.AvatarURL != nil {
 		user.AvatarURL = req.AvatarURL
 	}
+	if req.Timezone != nil {
+		if *req.Timezone != "" && !utils.IsValidTimezone(*req.Timezone) {
+			response.BadRequest(c, "无效的时区")
+			return
+		}
+		user.Timezone = *req.Timezone
+	}
 
 	if err := h.db.Save(&user).Error; err != nil {
 		response.InternalError(c, "更新失败")
@@ -1,10 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"fluent-life-backend/internal/config"
 	"fluent-life-backend/internal/hub"
 	"fluent-life-backend/internal/models"
 	"fluent-life-backend/internal/services"
-	"fluent-life-backend/pkg/auth"
 	"fluent-life-backend/pkg/response"
 	"log"
 	"net/http"
@@ -27,15 +28,17 @@ type WebSocketHandler struct {
 	hub                 *hub.RoomHub
 	db                  *gorm.DB
 	practiceRoomService *services.PracticeRoomService
-	jwtSecret           string
+	tokenService        *services.TokenService
+	blockService        *services.BlockService
 }
 
-func NewWebSocketHandler(hub *hub.RoomHub, db *gorm.DB, jwtSecret string) *WebSocketHandler {
+func NewWebSocketHandler(hub *hub.RoomHub, db *gorm.DB, cfg *config.Config) *WebSocketHandler {
 	return &WebSocketHandler{
 		hub:                 hub,
 		db:                  db,
 		practiceRoomService: services.NewPracticeRoomService(db),
-		jwtSecret:           jwtSecret,
+		tokenService:        services.NewTokenService(db, cfg),
+		blockService:        services.NewBlockService(db),
 	}
 }
 
@@ -70,7 +73,12 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	}
 
 	// 验证 token
-	claims, err := auth.ValidateToken(tokenStr, h.jwtSecret)
+	claims, err := h.tokenService.ValidateAccessToken(tokenStr)
+	if errors.Is(err, services.ErrUserSuspended) {
+		conn.WriteJSON(gin.H{"type": "error", "message": "账号已被停用"})
+		conn.Close()
+		return
+	}
 	if err != nil {
 		conn.WriteJSON(gin.H{"type": "error", "message": "无效的认证令牌"})
 		conn.Close()
@@ -108,6 +116,21 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 			return
 		}
 
+		// 被房主拉黑的用户不能进入房间
+		if room.UserID != userID {
+			blocked, err := h.blockService.IsBlocked(room.UserID, userID)
+			if err != nil {
+				conn.WriteJSON(gin.H{"type": "error", "message": "检查房间权限失败"})
+				conn.Close()
+				return
+			}
+			if blocked {
+				conn.WriteJSON(gin.H{"type": "error", "message": services.ErrBlockedByHost.Error()})
+				conn.Close()
+				return
+			}
+		}
+
 		// 检查用户是否是房间成员
 		var member models.PracticeRoomMember
 		if err := h.db.Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error; err != nil {
@@ -131,12 +154,13 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 
 	// 创建客户端
 	client := &hub.Client{
-		Hub:      h.hub,
-		Conn:     conn,
-		Send:     make(chan hub.Message, 512),
-		RoomID:   roomIDStr,
-		UserID:   userID.String(),
-		Username: user.Username,
+		Hub:       h.hub,
+		Conn:      conn,
+		Send:      make(chan hub.Message, 512),
+		RoomID:    roomIDStr,
+		UserID:    userID.String(),
+		SessionID: claims.SessionID.String(),
+		Username:  user.Username,
 		AvatarURL: func() string {
 			if user.AvatarURL != nil {
 				return *user.AvatarURL
@@ -112,6 +112,7 @@ type Client struct {
 	Send      chan Message // Buffered channel for outbound messages
 	RoomID    string
 	UserID    string
+	SessionID string // 建立连接时使用的登录会话ID
 	Username  string
 	AvatarURL string
 	OnLeave   func() // 离开房间的回调函数
@@ -151,6 +152,12 @@ type RoomHub struct {
 
 	// 等待匹配的客户端队列
 	WaitingClients []*Client
+
+	// MatchFilter 判断两个用户能否被匹配到一起（隐私设置等），为 nil 时不过滤
+	MatchFilter func(userA, userB string) bool
+
+	// 正在处理匹配请求的用户，避免同一用户的请求并发匹配
+	pendingMatches map[string]bool
 }
 
 var (
@@ -218,6 +225,7 @@ func NewRoomHub() *RoomHub {
 		Broadcast:      make(chan Message),
 		MatchRequests:  make(chan *Client),
 		WaitingClients: make([]*Client, 0),
+		pendingMatches: make(map[string]bool),
 	}
 }
 
@@ -376,26 +384,156 @@ func (h *RoomHub) Run() {
 			readPumpLog("[RoomHub.Run] ✅ Broadcast 消息已处理完成")
 
 		case reqClient := <-h.MatchRequests:
-			readPumpLog("收到匹配请求，用户ID: %s, 用户名: %s, 等待队列长度: %d, global在线数(去重): %d", reqClient.UserID, reqClient.Username, len(h.WaitingClients), len(h.GlobalByUserID))
-			h.Mutex.Lock()
-			if cur := h.GlobalByUserID[reqClient.UserID]; cur != nil {
-				reqClient = cur
+			// 匹配过滤可能查询数据库，放到独立的 goroutine 中，避免阻塞注册、注销和广播
+			go h.match(reqClient)
+		}
+	}
+}
+
+// match 为请求者寻找可匹配的等待用户，没有时加入等待队列。匹配过滤不持锁执行，
+// 选定对象后重新加锁确认请求者仍在匹配、对方仍在等待队列且双方都在线，否则继续尝试其他候选
+func (h *RoomHub) match(reqClient *Client) {
+	h.Mutex.Lock()
+	if h.pendingMatches[reqClient.UserID] {
+		h.Mutex.Unlock()
+		return // 同一用户的上一个匹配请求还在处理中
+	}
+	h.pendingMatches[reqClient.UserID] = true
+	defer func() {
+		h.Mutex.Lock()
+		delete(h.pendingMatches, reqClient.UserID)
+		h.Mutex.Unlock()
+	}()
+
+	readPumpLog("收到匹配请求，用户ID: %s, 用户名: %s, 等待队列长度: %d, global在线数(去重): %d", reqClient.UserID, reqClient.Username, len(h.WaitingClients), len(h.GlobalByUserID))
+	if cur := h.GlobalByUserID[reqClient.UserID]; cur != nil {
+		reqClient = cur
+	}
+	h.MatchingUsers[reqClient.UserID] = true
+	tried := make(map[string]bool)
+	candidates := h.waitingCandidates(reqClient, tried)
+	filter := h.MatchFilter
+	h.Mutex.Unlock()
+
+	for {
+		// 匹配过滤可能查询数据库，不持锁执行
+		var peer *Client
+		for _, c := range candidates {
+			tried[c.UserID] = true
+			if filter == nil || filter(reqClient.UserID, c.UserID) {
+				peer = c
+				break
 			}
+		}
 
-			// 先清理等待队列里已离线的用户（避免一直匹配到离线用户）
-			if len(h.WaitingClients) > 0 {
-				filtered := make([]*Client, 0, len(h.WaitingClients))
-				for _, c := range h.WaitingClients {
-					if cur := h.GlobalByUserID[c.UserID]; cur != nil {
-						filtered = append(filtered, cur)
-					}
-				}
-				h.WaitingClients = filtered
+		h.Mutex.Lock()
+		// 过滤期间请求者可能已取消匹配或断开连接
+		current := h.GlobalByUserID[reqClient.UserID]
+		if current == nil || !h.MatchingUsers[reqClient.UserID] {
+			h.Mutex.Unlock()
+			readPumpLog("[Match] 用户 %s (%s) 已取消匹配或断开连接，放弃本次匹配", reqClient.UserID, reqClient.Username)
+			return
+		}
+		reqClient = current
+
+		// 对方也可能已取消匹配或断开连接，此时继续尝试其他候选
+		if peer != nil {
+			if peerCurrent := h.GlobalByUserID[peer.UserID]; peerCurrent != nil && h.isWaiting(peer.UserID) {
+				peer = peerCurrent
+				h.removeWaiting(reqClient.UserID)
+				h.removeWaiting(peer.UserID)
+				delete(h.MatchingUsers, reqClient.UserID)
+				delete(h.MatchingUsers, peer.UserID)
+				h.Mutex.Unlock()
+
+				readPumpLog("[Match] ✅ 用户 %s (%s) 与 %s (%s) 匹配成功", reqClient.UserID, reqClient.Username, peer.UserID, peer.Username)
+				h.sendMatchSuccess(reqClient, peer, true)
+				h.sendMatchSuccess(peer, reqClient, false)
+				return
+			}
+		}
+
+		// 过滤期间新加入等待队列的用户还没有尝试过
+		candidates = h.waitingCandidates(reqClient, tried)
+		if len(candidates) == 0 {
+			if !h.isWaiting(reqClient.UserID) {
+				h.WaitingClients = append(h.WaitingClients, reqClient)
 			}
+			queueLen := len(h.WaitingClients)
+			h.Mutex.Unlock()
+			readPumpLog("[Match] 用户 %s (%s) 暂无可匹配对象，加入等待队列，队列长度: %d", reqClient.UserID, reqClient.Username, queueLen)
+			return
+		}
+		h.Mutex.Unlock()
+	}
+}
+
+// waitingCandidates 先清理等待队列中已离线的用户（避免一直匹配到离线用户），
+// 再返回可与请求者配对且尚未尝试过的用户。调用方需持有锁
+func (h *RoomHub) waitingCandidates(reqClient *Client, tried map[string]bool) []*Client {
+	filtered := make([]*Client, 0, len(h.WaitingClients))
+	candidates := make([]*Client, 0, len(h.WaitingClients))
+	for _, c := range h.WaitingClients {
+		cur := h.GlobalByUserID[c.UserID]
+		if cur == nil {
+			continue
+		}
+		filtered = append(filtered, cur)
+		if cur.UserID != reqClient.UserID && !tried[cur.UserID] {
+			candidates = append(candidates, cur)
+		}
+	}
+	h.WaitingClients = filtered
+	return candidates
+}
+
+// isWaiting 判断用户是否在等待匹配队列中，调用方需持有锁
+func (h *RoomHub) isWaiting(userID string) bool {
+	for _, c := range h.WaitingClients {
+		if c.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
 
-			// ...（后续内容省略，保持原文件其他部分不变）
+// removeWaiting 将用户移出等待匹配队列，调用方需持有锁
+func (h *RoomHub) removeWaiting(userID string) {
+	filtered := h.WaitingClients[:0]
+	for _, c := range h.WaitingClients {
+		if c.UserID != userID {
+			filtered = append(filtered, c)
 		}
 	}
+	h.WaitingClients = filtered
+}
+
+// CancelMatch 取消用户的 1v1 匹配请求
+func (h *RoomHub) CancelMatch(userID string) {
+	h.Mutex.Lock()
+	defer h.Mutex.Unlock()
+	h.removeWaiting(userID)
+	delete(h.MatchingUsers, userID)
+}
+
+// sendMatchSuccess 通知 client 已与 peer 匹配，initiator 为 true 的一方负责发起 WebRTC offer
+func (h *RoomHub) sendMatchSuccess(client, peer *Client, initiator bool) {
+	message := Message{
+		Type:   MessageType1v1MatchSuccess,
+		RoomID: client.RoomID,
+		Data: map[string]interface{}{
+			"peer_user_id":    peer.UserID,
+			"peer_username":   peer.Username,
+			"peer_avatar_url": peer.AvatarURL,
+			"initiator":       initiator,
+		},
+		Timestamp: time.Now().Unix(),
+	}
+	select {
+	case client.Send <- message:
+	default:
+		readPumpLog("[Match] ❌ 无法向用户 %s (%s) 发送匹配结果", client.UserID, client.Username)
+	}
 }
 
 // broadcastToRoom 向房间内所有客户端广播消息（排除发送者）
@@ -491,6 +629,55 @@ func (h *RoomHub) GetRoomMemberCount(roomID string) int {
 	return 0
 }
 
+// DisconnectSession 断开某个登录会话的所有 WebSocket 连接（会话被吊销时调用）
+func (h *RoomHub) DisconnectSession(sessionID string) {
+	h.disconnect(func(c *Client) bool { return c.SessionID == sessionID })
+}
+
+// DisconnectUser 断开某个用户的所有 WebSocket 连接
+func (h *RoomHub) DisconnectUser(userID string) {
+	h.disconnect(func(c *Client) bool { return c.UserID == userID })
+}
+
+// DisconnectOtherSessions 断开某个用户除指定会话外的所有 WebSocket 连接
+func (h *RoomHub) DisconnectOtherSessions(userID, keepSessionID string) {
+	h.disconnect(func(c *Client) bool { return c.UserID == userID && c.SessionID != keepSessionID })
+}
+
+// CloseRoom 通知所有连接房间已被关闭，并在短暂延迟后断开房间内的连接
+func (h *RoomHub) CloseRoom(roomID, reason string) {
+	h.broadcastToAll(Message{
+		Type:      MessageTypeRoomDeleted,
+		RoomID:    roomID,
+		Data:      map[string]interface{}{"room_id": roomID, "reason": reason},
+		Timestamp: time.Now().Unix(),
+	})
+
+	// 留出时间让 WritePump 把关闭通知发送出去
+	time.AfterFunc(time.Second, func() {
+		h.disconnect(func(c *Client) bool { return c.RoomID == roomID })
+	})
+}
+
+// disconnect 关闭满足条件的连接，ReadPump 随之退出并走正常的注销流程
+func (h *RoomHub) disconnect(match func(*Client) bool) {
+	h.Mutex.RLock()
+	targets := make([]*Client, 0)
+	for _, room := range h.Rooms {
+		for client := range room {
+			if match(client) {
+				targets = append(targets, client)
+			}
+		}
+	}
+	h.Mutex.RUnlock()
+
+	for _, client := range targets {
+		readPumpLog("[disconnect] 断开用户 %s (%s) 的连接，房间ID: %s, 会话ID: %s", client.UserID, client.Username, client.RoomID, client.SessionID)
+		client.Conn.Close()
+	}
+}
+
 // GetOnMicUsers 获取房间中上麦的用户ID列表
 func (h *RoomHub) GetOnMicUsers(roomID string) []string {
 	h.Mutex.RLock()
@@ -707,6 +894,7 @@ func (c *Client) ReadPump() {
 			c.Hub.MatchRequests <- c
 		case MessageType1v1MatchCancel:
 			readPumpLog("[ReadPump] 收到用户 %s (%s) 的 1v1 匹配取消请求", c.UserID, c.Username)
+			c.Hub.CancelMatch(c.UserID)
 		default:
 			readPumpLog("[ReadPump] ⚠️ 收到未直接处理的消息类型: '%s' (来自用户 %s, 房间 %s)", message.Type, c.UserID, c.RoomID)
 			readPumpLog("[ReadPump] 原始消息详情: %+v", message)
@@ -0,0 +1,189 @@
+// Package meditation 冥想阶段定义：每个阶段的目标时长、完成所需天数、前置阶段和展示信息，
+// 从 YAML 文件加载，新增阶段或调整门槛无需改代码
+package meditation
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// Stage 单个冥想阶段
+type Stage struct {
+	ID             int    `mapstructure:"id" json:"id"`
+	Name           string `mapstructure:"name" json:"name"`
+	Description    string `mapstructure:"description" json:"description,omitempty"`
+	TargetDuration int    `mapstructure:"target_duration" json:"target_duration"` // 单次冥想达到该时长（秒）才计入有效天数
+	RequiredDays   int    `mapstructure:"required_days" json:"required_days"`     // 有效天数达到该值视为完成本阶段
+	Prerequisites  []int  `mapstructure:"prerequisites" json:"prerequisites"`     // 全部完成后解锁本阶段，为空表示默认解锁
+}
+
+// StageSet 一个版本的全部阶段定义
+type StageSet struct {
+	Version int     `mapstructure:"version" json:"version"`
+	Stages  []Stage `mapstructure:"stages" json:"stages"`
+
+	byID map[int]*Stage
+}
+
+// Default 内置的阶段定义，未提供配置文件时使用
+func Default() *StageSet {
+	set := &StageSet{
+		Version: 1,
+		Stages: []Stage{
+			{ID: 1, Name: "呼吸觉察", Description: "每天完成 5 分钟冥想", TargetDuration: 300, RequiredDays: 14},
+			{ID: 2, Name: "身体放松", Description: "每天完成 12 分钟冥想", TargetDuration: 720, RequiredDays: 14, Prerequisites: []int{1}},
+			{ID: 3, Name: "即时平静", Description: "随时完成 30 秒的快速冥想", TargetDuration: 30, RequiredDays: 14, Prerequisites: []int{1, 2}},
+		},
+	}
+	if err := set.init(); err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// Load 从 YAML 文件加载阶段定义；path 为空或文件不存在时使用内置定义
+func Load(path string) (*StageSet, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取冥想阶段配置失败: %w", err)
+	}
+	var set StageSet
+	if err := v.Unmarshal(&set); err != nil {
+		return nil, fmt.Errorf("解析冥想阶段配置失败: %w", err)
+	}
+	if err := set.init(); err != nil {
+		return nil, fmt.Errorf("冥想阶段配置无效: %w", err)
+	}
+	return &set, nil
+}
+
+// init 校验阶段定义（编号唯一、前置阶段存在且无循环依赖）并建立索引
+func (s *StageSet) init() error {
+	if len(s.Stages) == 0 {
+		return errors.New("至少需要一个阶段")
+	}
+	sort.Slice(s.Stages, func(i, j int) bool { return s.Stages[i].ID < s.Stages[j].ID })
+
+	s.byID = make(map[int]*Stage, len(s.Stages))
+	for i := range s.Stages {
+		stage := &s.Stages[i]
+		if stage.ID <= 0 {
+			return fmt.Errorf("阶段编号必须为正数: %d", stage.ID)
+		}
+		if _, dup := s.byID[stage.ID]; dup {
+			return fmt.Errorf("阶段编号重复: %d", stage.ID)
+		}
+		if stage.TargetDuration < 0 || stage.RequiredDays <= 0 {
+			return fmt.Errorf("阶段 %d 的目标时长或完成天数无效", stage.ID)
+		}
+		s.byID[stage.ID] = stage
+	}
+
+	// 前置阶段必须存在，且不能形成循环
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[int]int, len(s.Stages))
+	var visit func(id int) error
+	visit = func(id int) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("阶段 %d 的前置阶段存在循环依赖", id)
+		case done:
+			return nil
+		}
+		state[id] = visiting
+		for _, prereq := range s.byID[id].Prerequisites {
+			if _, ok := s.byID[prereq]; !ok {
+				return fmt.Errorf("阶段 %d 的前置阶段 %d 不存在", id, prereq)
+			}
+			if err := visit(prereq); err != nil {
+				return err
+			}
+		}
+		state[id] = done
+		return nil
+	}
+	for _, stage := range s.Stages {
+		if err := visit(stage.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stage 按编号查找阶段
+func (s *StageSet) Stage(id int) (*Stage, bool) {
+	stage, ok := s.byID[id]
+	return stage, ok
+}
+
+// Progress 用户在某个阶段的进度
+type Progress struct {
+	CompletedDays int
+	Unlocked      bool
+}
+
+// Requirement 解锁某个阶段还需完成的前置阶段
+type Requirement struct {
+	Stage         int `json:"stage"`
+	RequiredDays  int `json:"required_days"`
+	CompletedDays int `json:"completed_days"`
+	RemainingDays int `json:"remaining_days"`
+}
+
+// IsCompleted 阶段是否已完成（有效天数达到要求）
+func (s *StageSet) IsCompleted(id int, progress map[int]Progress) bool {
+	stage, ok := s.byID[id]
+	return ok && progress[id].CompletedDays >= stage.RequiredDays
+}
+
+// Requirements 解锁阶段尚未满足的前置条件，为空表示可以解锁
+func (s *StageSet) Requirements(id int, progress map[int]Progress) []Requirement {
+	stage, ok := s.byID[id]
+	if !ok {
+		return nil
+	}
+	var missing []Requirement
+	for _, prereqID := range stage.Prerequisites {
+		prereq := s.byID[prereqID]
+		completed := progress[prereqID].CompletedDays
+		if completed < prereq.RequiredDays {
+			missing = append(missing, Requirement{
+				Stage:         prereqID,
+				RequiredDays:  prereq.RequiredDays,
+				CompletedDays: completed,
+				RemainingDays: prereq.RequiredDays - completed,
+			})
+		}
+	}
+	return missing
+}
+
+// NewlyUnlocked 根据当前进度返回应解锁但尚未解锁的阶段
+func (s *StageSet) NewlyUnlocked(progress map[int]Progress) []int {
+	var unlocked []int
+	for _, stage := range s.Stages {
+		if progress[stage.ID].Unlocked {
+			continue
+		}
+		if len(s.Requirements(stage.ID, progress)) == 0 {
+			unlocked = append(unlocked, stage.ID)
+		}
+	}
+	return unlocked
+}
@@ -0,0 +1,241 @@
+package meditation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeStages(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stages.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFallsBackToDefault(t *testing.T) {
+	for _, path := range []string{"", filepath.Join(t.TempDir(), "missing.yaml")} {
+		set, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load(%q): %v", path, err)
+		}
+		if !reflect.DeepEqual(set.Stages, Default().Stages) {
+			t.Errorf("Load(%q) did not return the default stages", path)
+		}
+	}
+}
+
+func TestLoadBundledConfig(t *testing.T) {
+	set, err := Load(filepath.Join("..", "..", "configs", "meditation_stages.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Stages) == 0 {
+		t.Fatal("no stages loaded")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string // 为空表示应加载成功
+	}{
+		{
+			name: "valid",
+			yaml: `
+version: 2
+stages:
+  - {id: 2, name: b, target_duration: 60, required_days: 3, prerequisites: [1]}
+  - {id: 1, name: a, target_duration: 30, required_days: 2}
+`,
+		},
+		{
+			name:    "no stages",
+			yaml:    "version: 1\nstages: []\n",
+			wantErr: "至少需要一个阶段",
+		},
+		{
+			name: "non-positive id",
+			yaml: `
+stages:
+  - {id: 0, name: a, required_days: 1}
+`,
+			wantErr: "阶段编号必须为正数",
+		},
+		{
+			name: "duplicate id",
+			yaml: `
+stages:
+  - {id: 1, name: a, required_days: 1}
+  - {id: 1, name: b, required_days: 1}
+`,
+			wantErr: "阶段编号重复",
+		},
+		{
+			name: "invalid required days",
+			yaml: `
+stages:
+  - {id: 1, name: a, required_days: 0}
+`,
+			wantErr: "目标时长或完成天数无效",
+		},
+		{
+			name: "negative target duration",
+			yaml: `
+stages:
+  - {id: 1, name: a, target_duration: -1, required_days: 1}
+`,
+			wantErr: "目标时长或完成天数无效",
+		},
+		{
+			name: "unknown prerequisite",
+			yaml: `
+stages:
+  - {id: 1, name: a, required_days: 1}
+  - {id: 2, name: b, required_days: 1, prerequisites: [3]}
+`,
+			wantErr: "阶段 2 的前置阶段 3 不存在",
+		},
+		{
+			name: "self prerequisite",
+			yaml: `
+stages:
+  - {id: 1, name: a, required_days: 1, prerequisites: [1]}
+`,
+			wantErr: "循环依赖",
+		},
+		{
+			name: "cycle",
+			yaml: `
+stages:
+  - {id: 1, name: a, required_days: 1}
+  - {id: 2, name: b, required_days: 1, prerequisites: [1, 4]}
+  - {id: 3, name: c, required_days: 1, prerequisites: [2]}
+  - {id: 4, name: d, required_days: 1, prerequisites: [3]}
+`,
+			wantErr: "循环依赖",
+		},
+		{
+			name:    "malformed yaml",
+			yaml:    "stages: [\n",
+			wantErr: "读取冥想阶段配置失败",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			set, err := Load(writeStages(t, tc.yaml))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if set.Stages[0].ID != 1 || set.Stages[1].ID != 2 {
+					t.Errorf("stages not sorted by id: %+v", set.Stages)
+				}
+				if _, ok := set.Stage(2); !ok {
+					t.Error("stage 2 not indexed")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	set := Default()
+	tests := []struct {
+		name     string
+		stage    int
+		progress map[int]Progress
+		want     []Requirement
+	}{
+		{"no prerequisites", 1, nil, nil},
+		{"unknown stage", 99, nil, nil},
+		{
+			name:     "prerequisite not started",
+			stage:    2,
+			progress: nil,
+			want:     []Requirement{{Stage: 1, RequiredDays: 14, CompletedDays: 0, RemainingDays: 14}},
+		},
+		{
+			name:     "prerequisite in progress",
+			stage:    2,
+			progress: map[int]Progress{1: {CompletedDays: 10, Unlocked: true}},
+			want:     []Requirement{{Stage: 1, RequiredDays: 14, CompletedDays: 10, RemainingDays: 4}},
+		},
+		{
+			name:     "prerequisite completed",
+			stage:    2,
+			progress: map[int]Progress{1: {CompletedDays: 14, Unlocked: true}},
+		},
+		{
+			name:     "one of two prerequisites missing",
+			stage:    3,
+			progress: map[int]Progress{1: {CompletedDays: 20, Unlocked: true}, 2: {CompletedDays: 3, Unlocked: true}},
+			want:     []Requirement{{Stage: 2, RequiredDays: 14, CompletedDays: 3, RemainingDays: 11}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := set.Requirements(tc.stage, tc.progress); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsCompleted(t *testing.T) {
+	set := Default()
+	progress := map[int]Progress{1: {CompletedDays: 14}, 2: {CompletedDays: 13}}
+	tests := []struct {
+		stage int
+		want  bool
+	}{
+		{1, true},
+		{2, false},
+		{3, false},
+		{99, false},
+	}
+	for _, tc := range tests {
+		if got := set.IsCompleted(tc.stage, progress); got != tc.want {
+			t.Errorf("IsCompleted(%d) = %v, want %v", tc.stage, got, tc.want)
+		}
+	}
+}
+
+func TestNewlyUnlocked(t *testing.T) {
+	set := Default()
+	tests := []struct {
+		name     string
+		progress map[int]Progress
+		want     []int
+	}{
+		{"new user unlocks stages without prerequisites", nil, []int{1}},
+		{"nothing new", map[int]Progress{1: {CompletedDays: 5, Unlocked: true}}, nil},
+		{"first stage completed", map[int]Progress{1: {CompletedDays: 14, Unlocked: true}}, []int{2}},
+		{
+			name:     "all prerequisites completed",
+			progress: map[int]Progress{1: {CompletedDays: 14, Unlocked: true}, 2: {CompletedDays: 14, Unlocked: true}},
+			want:     []int{3},
+		},
+		{
+			name:     "multiple stages at once",
+			progress: map[int]Progress{1: {CompletedDays: 14, Unlocked: true}, 2: {CompletedDays: 14}},
+			want:     []int{2, 3},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := set.NewlyUnlocked(tc.progress); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,19 +1,37 @@
 package middleware
 
 import (
+	"errors"
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/services"
 	"fluent-life-backend/internal/utils"
+	"net/http"
 	"strings"
 
-	"fluent-life-backend/pkg/auth"
 	"fluent-life-backend/pkg/response"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func Auth(secret string) gin.HandlerFunc {
+// Auth 只接受登录会话的访问令牌，个人访问令牌会被拒绝
+func Auth(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
+	return newAuth(db, cfg, "")
+}
+
+// ScopedAuth 同时接受访问令牌和个人访问令牌。使用个人访问令牌时，
+// GET/HEAD 请求需要 <resource>:read 权限，其他请求需要 <resource>:write 权限
+func ScopedAuth(db *gorm.DB, cfg *config.Config, resource string) gin.HandlerFunc {
+	return newAuth(db, cfg, resource)
+}
+
+func newAuth(db *gorm.DB, cfg *config.Config, resource string) gin.HandlerFunc {
+	tokenService := services.NewTokenService(db, cfg)
+	sessionService := services.NewSessionService(db, cfg)
+	patService := services.NewPersonalAccessTokenService(db, cfg)
 	return func(c *gin.Context) {
 		utils.APILog("[Auth Middleware] 收到请求: %s %s", c.Request.Method, c.Request.URL.Path)
-		
+
 		// 允许 OPTIONS 预检请求通过（CORS 预检）
 		if c.Request.Method == "OPTIONS" {
 			utils.APILog("[Auth Middleware] OPTIONS 预检请求，直接通过")
@@ -37,7 +55,18 @@ func Auth(secret string) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := auth.ValidateToken(parts[1], secret)
+		if services.IsPersonalAccessToken(parts[1]) {
+			authenticatePersonalAccessToken(c, patService, parts[1], resource)
+			return
+		}
+
+		claims, err := tokenService.ValidateAccessToken(parts[1])
+		if errors.Is(err, services.ErrUserSuspended) {
+			utils.APILog("[Auth Middleware] ❌ 账号已被停用")
+			response.Forbidden(c, "账号已被停用")
+			c.Abort()
+			return
+		}
 		if err != nil {
 			utils.APILog("[Auth Middleware] ❌ 无效的认证令牌: %v", err)
 			response.Unauthorized(c, "无效的认证令牌")
@@ -47,6 +76,57 @@ func Auth(secret string) gin.HandlerFunc {
 
 		utils.APILog("[Auth Middleware] ✅ 认证成功，用户ID: %s", claims.UserID)
 		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
+		c.Set("role", claims.Role)
+
+		// 更新会话最近活跃时间（失败不影响请求）
+		if err := sessionService.Touch(claims.SessionID, c.ClientIP()); err != nil {
+			utils.APILog("[Auth Middleware] ⚠️ 更新会话活跃时间失败: %v", err)
+		}
+		// 用户未设置时区时，使用客户端上报的时区；已设置时不再写库
+		if timezone := c.GetHeader("X-Timezone"); timezone != "" && claims.Timezone == "" {
+			if err := services.DetectTimezone(db, claims.UserID, timezone); err != nil {
+				utils.APILog("[Auth Middleware] ⚠️ 记录用户时区失败: %v", err)
+			}
+		}
 		c.Next()
 	}
 }
+
+// authenticatePersonalAccessToken 校验个人访问令牌及其权限范围
+func authenticatePersonalAccessToken(c *gin.Context, patService *services.PersonalAccessTokenService, plaintext, resource string) {
+	if resource == "" {
+		utils.APILog("[Auth Middleware] ❌ 个人访问令牌不能访问 %s %s", c.Request.Method, c.Request.URL.Path)
+		response.Forbidden(c, "个人访问令牌不能访问该接口")
+		c.Abort()
+		return
+	}
+
+	token, role, err := patService.Authenticate(plaintext, c.ClientIP())
+	if errors.Is(err, services.ErrUserSuspended) {
+		utils.APILog("[Auth Middleware] ❌ 账号已被停用")
+		response.Forbidden(c, "账号已被停用")
+		c.Abort()
+		return
+	}
+	if err != nil {
+		utils.APILog("[Auth Middleware] ❌ 无效的个人访问令牌: %v", err)
+		response.Unauthorized(c, "无效的认证令牌")
+		c.Abort()
+		return
+	}
+
+	write := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead
+	if !token.Allows(resource, write) {
+		utils.APILog("[Auth Middleware] ❌ 访问令牌 %s 无权 %s %s", token.ID, c.Request.Method, c.Request.URL.Path)
+		response.Forbidden(c, services.ErrAccessTokenScopeDenied.Error())
+		c.Abort()
+		return
+	}
+
+	utils.APILog("[Auth Middleware] ✅ 个人访问令牌认证成功，用户ID: %s，令牌ID: %s", token.UserID, token.ID)
+	c.Set("user_id", token.UserID)
+	c.Set("access_token_id", token.ID)
+	c.Set("role", role)
+	c.Next()
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole 仅允许指定角色访问，需放在 Auth 之后
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := utils.GetRole(c)
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		utils.APILog("[RequireRole] ❌ 角色 %q 无权访问 %s %s", role, c.Request.Method, c.Request.URL.Path)
+		response.Forbidden(c, "无权限执行该操作")
+		c.Abort()
+	}
+}
+
+// RequirePermission 仅允许拥有指定权限的角色访问，需放在 Auth 之后
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := utils.GetRole(c)
+		if !models.HasPermission(role, permission) {
+			utils.APILog("[RequirePermission] ❌ 角色 %q 缺少权限 %s", role, permission)
+			response.Forbidden(c, "无权限执行该操作")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireWritePermission 对写请求（非 GET/HEAD）要求指定权限，读请求直接放行，需放在 Auth 之后
+func RequireWritePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		role := utils.GetRole(c)
+		if !models.HasPermission(role, permission) {
+			utils.APILog("[RequireWritePermission] ❌ 角色 %q 缺少权限 %s: %s %s", role, permission, c.Request.Method, c.Request.URL.Path)
+			if role == models.RoleGuest {
+				response.Forbidden(c, "游客账号不能使用该功能，请先绑定手机号或邮箱")
+			} else {
+				response.Forbidden(c, "无权限执行该操作")
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 作业状态
+const (
+	AssignmentActive    = "active"
+	AssignmentCompleted = "completed"
+	AssignmentOverdue   = "overdue"   // 截止时仍未完成
+	AssignmentCancelled = "cancelled" // 治疗师取消或客户撤销了授权
+)
+
+// Assignment 治疗师给客户布置的练习作业，例如"本周 3 次气流练习，每次 10 分钟"。
+// 客户在 StartsAt 与 DueAt 之间记录的同类型训练（时长不少于 MinDuration）会自动计入完成次数
+type Assignment struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TherapistID    uuid.UUID  `gorm:"type:uuid;not null;index:idx_assignments_therapist_id" json:"therapist_id"`
+	ClientID       uuid.UUID  `gorm:"type:uuid;not null;index:idx_assignments_client_status" json:"client_id"`
+	Type           string     `gorm:"type:varchar(20);not null" json:"type"` // 与 TrainingRecord.Type 相同
+	TargetCount    int        `gorm:"not null;default:1" json:"target_count"`
+	MinDuration    int        `gorm:"not null;default:0" json:"min_duration"` // 每次训练的最少时长（秒），0 表示不限
+	CompletedCount int        `gorm:"not null;default:0" json:"completed_count"`
+	Notes          string     `gorm:"type:text" json:"notes,omitempty"`
+	StartsAt       time.Time  `gorm:"not null" json:"starts_at"`
+	DueAt          time.Time  `gorm:"not null;index:idx_assignments_due_at" json:"due_at"`
+	Status         string     `gorm:"type:varchar(20);not null;default:'active';index:idx_assignments_client_status" json:"status"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Therapist *User `gorm:"foreignKey:TherapistID" json:"therapist,omitempty"`
+	Client    *User `gorm:"foreignKey:ClientID" json:"client,omitempty"`
+}
+
+func (a *Assignment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsValidAssignmentStatus 判断作业状态是否存在
+func IsValidAssignmentStatus(status string) bool {
+	switch status {
+	case AssignmentActive, AssignmentCompleted, AssignmentOverdue, AssignmentCancelled:
+		return true
+	}
+	return false
+}
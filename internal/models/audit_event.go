@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 审计事件类型
+const (
+	AuditRegister          = "auth.register"
+	AuditLogin             = "auth.login"
+	AuditLoginFailed       = "auth.login_failed"
+	AuditTwoFactorRequired = "auth.2fa_challenge"
+	AuditTwoFactorFailed   = "auth.2fa_failed"
+	AuditTokenRefresh      = "auth.token_refresh"
+	AuditTokenReuse        = "auth.token_reuse"
+	AuditLogout            = "auth.logout"
+	AuditLogoutAll         = "auth.logout_all"
+	AuditPasswordChange    = "auth.password_change"
+	AuditPasswordReset     = "auth.password_reset"
+	AuditGuestCreate       = "auth.guest_create"
+	AuditGuestUpgrade      = "auth.guest_upgrade"
+
+	AuditPostDelete    = "community.post_delete"
+	AuditCommentDelete = "community.comment_delete"
+
+	AuditAccountDeletionRequested = "account.deletion_requested"
+	AuditAccountDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountDeleted           = "account.deleted"
+	AuditGuestPurged              = "account.guest_purged"
+	AuditIdentifierChange         = "account.identifier_change"
+	AuditIdentifierRevert         = "account.identifier_revert"
+
+	AuditAdminPostDelete    = "admin.post_delete"
+	AuditAdminCommentDelete = "admin.comment_delete"
+	AuditAdminRoomClose     = "admin.room_close"
+	AuditAdminUserSuspend   = "admin.user_suspend"
+	AuditAdminUserUnsuspend = "admin.user_unsuspend"
+	AuditAdminRoleChange    = "admin.role_change"
+
+	AuditTherapistLink          = "therapist.link"
+	AuditTherapistConsentUpdate = "therapist.consent_update"
+	AuditTherapistRevoke        = "therapist.revoke"
+)
+
+// 审计事件目标类型
+const (
+	AuditTargetUser    = "user"
+	AuditTargetSession = "session"
+	AuditTargetPost    = "post"
+	AuditTargetComment = "comment"
+	AuditTargetRoom    = "practice_room"
+)
+
+// AuditEvent 安全审计日志，只追加不修改（数据库触发器禁止 UPDATE，只有保留期清理会删除）
+type AuditEvent struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ActorID    *uuid.UUID `gorm:"type:uuid;index:idx_audit_events_actor_id" json:"actor_id,omitempty"` // 为空表示匿名或系统
+	Action     string     `gorm:"type:varchar(64);not null;index:idx_audit_events_action" json:"action"`
+	TargetType string     `gorm:"type:varchar(32);index:idx_audit_events_target,priority:1" json:"target_type,omitempty"`
+	TargetID   string     `gorm:"type:varchar(64);index:idx_audit_events_target,priority:2" json:"target_id,omitempty"`
+	IP         string     `gorm:"type:varchar(45)" json:"ip,omitempty"`
+	UserAgent  string     `gorm:"type:varchar(500)" json:"user_agent,omitempty"`
+	Metadata   JSONB      `gorm:"type:jsonb" json:"metadata,omitempty"`
+	CreatedAt  time.Time  `gorm:"not null;index:idx_audit_events_created_at" json:"created_at"`
+}
+
+func (e *AuditEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// auditEventsAppendOnlySQL 禁止修改已写入的审计事件
+const auditEventsAppendOnlySQL = `
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_no_update ON audit_events;
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+	FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+`
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 临床记录版本对应的操作
+const (
+	NoteActionCreate = "create"
+	NoteActionUpdate = "update"
+	NoteActionDelete = "delete"
+)
+
+// ClinicalNote 治疗师对客户的临床记录，默认只有撰写的治疗师可见，可选择分享给客户。
+// 每次修改都会在 ClinicalNoteVersion 中保存一份快照，删除只做标记
+type ClinicalNote struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TherapistID       uuid.UUID  `gorm:"type:uuid;not null;index:idx_clinical_notes_therapist_client" json:"therapist_id"`
+	ClientID          uuid.UUID  `gorm:"type:uuid;not null;index:idx_clinical_notes_therapist_client;index:idx_clinical_notes_client_id" json:"client_id"`
+	SessionDate       time.Time  `gorm:"type:date;not null" json:"session_date"`
+	Content           string     `gorm:"type:text;not null" json:"content"`
+	Tags              StringList `gorm:"type:jsonb;not null" json:"tags"`
+	TrainingRecordIDs StringList `gorm:"type:jsonb;not null" json:"training_record_ids"` // 关联的训练记录
+	SpeechAnalysis    string     `gorm:"type:text" json:"speech_analysis,omitempty"`     // 关联的 AI 语音分析结果
+	SharedWithClient  bool       `gorm:"not null;default:false" json:"shared_with_client"`
+	Version           int        `gorm:"not null;default:1" json:"version"`
+	DeletedAt         *time.Time `gorm:"index:idx_clinical_notes_deleted_at" json:"-"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Therapist *User `gorm:"foreignKey:TherapistID" json:"therapist,omitempty"`
+	Client    *User `gorm:"foreignKey:ClientID" json:"client,omitempty"`
+}
+
+func (n *ClinicalNote) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
+
+// ClinicalNoteVersion 临床记录每个版本的完整快照，只追加不修改
+type ClinicalNoteVersion struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	NoteID            uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_clinical_note_versions_note_version" json:"note_id"`
+	Version           int        `gorm:"not null;uniqueIndex:idx_clinical_note_versions_note_version" json:"version"`
+	Action            string     `gorm:"type:varchar(10);not null" json:"action"` // create | update | delete
+	EditorID          uuid.UUID  `gorm:"type:uuid;not null" json:"editor_id"`
+	SessionDate       time.Time  `gorm:"type:date;not null" json:"session_date"`
+	Content           string     `gorm:"type:text;not null" json:"content"`
+	Tags              StringList `gorm:"type:jsonb;not null" json:"tags"`
+	TrainingRecordIDs StringList `gorm:"type:jsonb;not null" json:"training_record_ids"`
+	SpeechAnalysis    string     `gorm:"type:text" json:"speech_analysis,omitempty"`
+	SharedWithClient  bool       `gorm:"not null" json:"shared_with_client"`
+	CreatedAt         time.Time  `json:"created_at"`
+
+	Note ClinicalNote `gorm:"foreignKey:NoteID" json:"-"`
+}
+
+func (v *ClinicalNoteVersion) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// NewClinicalNoteVersion 根据记录当前内容生成版本快照
+func NewClinicalNoteVersion(note *ClinicalNote, action string, editorID uuid.UUID) ClinicalNoteVersion {
+	return ClinicalNoteVersion{
+		NoteID:            note.ID,
+		Version:           note.Version,
+		Action:            action,
+		EditorID:          editorID,
+		SessionDate:       note.SessionDate,
+		Content:           note.Content,
+		Tags:              note.Tags,
+		TrainingRecordIDs: note.TrainingRecordIDs,
+		SpeechAnalysis:    note.SpeechAnalysis,
+		SharedWithClient:  note.SharedWithClient,
+	}
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 目标周期
+const (
+	GoalDaily  = "daily"
+	GoalWeekly = "weekly" // 按用户当地日历，每周从周一开始
+)
+
+// 目标指标
+const (
+	GoalMetricMinutes  = "minutes"  // 训练分钟数
+	GoalMetricSessions = "sessions" // 训练次数
+)
+
+// Goal 用户设定的练习目标，例如"每天 15 分钟"或"每周 3 次脱敏训练"。
+// 进度根据当前周期内的训练记录实时计算，每个周期结束后写入 GoalPeriod
+type Goal struct {
+	ID     uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;index:idx_goals_user_id" json:"user_id"`
+	Period string    `gorm:"type:varchar(20);not null" json:"period"`
+	Metric string    `gorm:"type:varchar(20);not null" json:"metric"`
+	Type   string    `gorm:"type:varchar(20);not null;default:''" json:"type,omitempty"` // 只统计该类型的训练，为空表示全部类型
+	Target int       `gorm:"not null" json:"target"`
+	Active bool      `gorm:"not null;default:true;index:idx_goals_active_next" json:"active"`
+	// 下一个待结算周期的开始时间，该周期结束后由后台任务写入历史
+	NextPeriodStart time.Time `gorm:"not null;index:idx_goals_active_next" json:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (g *Goal) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == uuid.Nil {
+		g.ID = uuid.New()
+	}
+	return nil
+}
+
+// GoalPeriod 目标在一个周期内的结果。周期内达成时立即写入，周期结束后更新为最终完成量
+type GoalPeriod struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	GoalID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_goal_periods_goal_start" json:"goal_id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index:idx_goal_periods_user_id" json:"user_id"`
+	PeriodStart time.Time  `gorm:"not null;uniqueIndex:idx_goal_periods_goal_start" json:"period_start"`
+	PeriodEnd   time.Time  `gorm:"not null" json:"period_end"`
+	Target      int        `gorm:"not null" json:"target"` // 当时的目标值
+	Achieved    int        `gorm:"not null;default:0" json:"achieved"`
+	Hit         bool       `gorm:"not null;default:false" json:"hit"`
+	HitAt       *time.Time `json:"hit_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (p *GoalPeriod) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IdentifierChange 邮箱/手机号变更记录。变更后会通知原邮箱/手机号，
+// 在撤销期限内可凭撤销令牌恢复原值
+type IdentifierChange struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID          uuid.UUID  `gorm:"type:uuid;not null;index:idx_identifier_changes_user_id" json:"user_id"`
+	Field           string     `gorm:"type:varchar(10);not null" json:"field"` // email | phone
+	OldValue        *string    `gorm:"type:varchar(255)" json:"old_value,omitempty"`
+	NewValue        string     `gorm:"type:varchar(255);not null" json:"new_value"`
+	RevertTokenHash *string    `gorm:"type:varchar(64);uniqueIndex" json:"-"` // 没有原值时无需撤销
+	RevertExpiresAt *time.Time `json:"revert_expires_at,omitempty"`
+	RevertedAt      *time.Time `json:"reverted_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (c *IdentifierChange) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
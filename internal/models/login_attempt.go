@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LoginAttempt 登录失败计数，按账号标识或 IP 分别统计
+type LoginAttempt struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Scope        string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_login_attempts_scope_key" json:"scope"` // 'identifier' | 'ip'
+	Key          string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_login_attempts_scope_key" json:"key"`
+	Failures     int        `gorm:"not null;default:0" json:"failures"`
+	LastFailedAt time.Time  `gorm:"not null;index:idx_login_attempts_last_failed_at" json:"last_failed_at"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+}
+
+func (a *LoginAttempt) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -10,7 +10,7 @@ import (
 type MeditationProgress struct {
 	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	UserID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_meditation_progress_user_stage" json:"user_id"`
-	Stage         int       `gorm:"not null;uniqueIndex:idx_meditation_progress_user_stage" json:"stage"` // 1-3
+	Stage         int       `gorm:"not null;uniqueIndex:idx_meditation_progress_user_stage" json:"stage"` // 阶段编号，见 configs/meditation_stages.yaml
 	CompletedDays int       `gorm:"not null;default:0" json:"completed_days"`
 	Unlocked      bool      `gorm:"not null;default:false" json:"unlocked"`
 	TotalTime     int       `gorm:"not null;default:0" json:"total_time"` // 新增字段：总冥想时长（秒）
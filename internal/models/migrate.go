@@ -2,8 +2,28 @@ package models
 
 import "gorm.io/gorm"
 
+// trainingRecordsTimestamptzSQL 把旧的 training_records.timestamp (不带时区) 转换为 timestamptz。
+// 旧数据按数据库连接时区 Asia/Shanghai 写入，转换时按该时区解释，按用户当地日期统计依赖 timestamptz
+const trainingRecordsTimestamptzSQL = `
+DO $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'training_records'
+			AND column_name = 'timestamp' AND data_type = 'timestamp without time zone'
+	) THEN
+		ALTER TABLE training_records
+			ALTER COLUMN "timestamp" TYPE TIMESTAMPTZ USING "timestamp" AT TIME ZONE 'Asia/Shanghai';
+	END IF;
+END $$;
+`
+
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	if err := db.Exec(trainingRecordsTimestamptzSQL).Error; err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(
 		&User{},
 		&VerificationCode{},
 		&TrainingRecord{},
@@ -18,11 +38,28 @@ func AutoMigrate(db *gorm.DB) error {
 		&PracticeRoomMember{},
 		&Follow{},
 		&PostCollection{},
-	)
-}
-
-
-
-
-
+		&UserSession{},
+		&RefreshToken{},
+		&LoginAttempt{},
+		&UserIdentity{},
+		&OAuthState{},
+		&TwoFactor{},
+		&RecoveryCode{},
+		&PersonalAccessToken{},
+		&AuditEvent{},
+		&PrivacySettings{},
+		&UserBlock{},
+		&IdentifierChange{},
+		&TherapistLink{},
+		&Assignment{},
+		&Notification{},
+		&ClinicalNote{},
+		&ClinicalNoteVersion{},
+		&Goal{},
+		&GoalPeriod{},
+	); err != nil {
+		return err
+	}
 
+	return db.Exec(auditEventsAppendOnlySQL).Error
+}
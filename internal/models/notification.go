@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 站内通知类型
+const (
+	NotificationAssignmentCompleted = "assignment_completed"
+	NotificationAssignmentOverdue   = "assignment_overdue"
+	NotificationGoalCompleted       = "goal_completed"
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index:idx_notifications_user_created" json:"user_id"`
+	Type      string     `gorm:"type:varchar(50);not null" json:"type"`
+	Title     string     `gorm:"type:varchar(200);not null" json:"title"`
+	Body      string     `gorm:"type:text" json:"body,omitempty"`
+	Data      JSONB      `gorm:"type:jsonb" json:"data,omitempty"` // 关联对象的ID等，便于客户端跳转
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `gorm:"index:idx_notifications_user_created" json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 个人访问令牌的权限范围，格式为 <资源>:<read|write>
+const (
+	ScopeTrainingRead   = "training:read"
+	ScopeTrainingWrite  = "training:write"
+	ScopeCommunityRead  = "community:read"
+	ScopeCommunityWrite = "community:write"
+)
+
+// TokenScopes 可授予个人访问令牌的全部权限范围
+var TokenScopes = []string{ScopeTrainingRead, ScopeTrainingWrite, ScopeCommunityRead, ScopeCommunityWrite}
+
+// IsValidScope 判断权限范围是否存在
+func IsValidScope(scope string) bool {
+	for _, s := range TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// StringList 以 JSON 数组保存的字符串列表
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return json.Marshal([]string{})
+	}
+	return json.Marshal(l)
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return json.Unmarshal([]byte(value.(string)), l)
+	}
+	return json.Unmarshal(bytes, l)
+}
+
+// Contains 是否包含指定字符串
+func (l StringList) Contains(s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonalAccessToken 用户为脚本、集成创建的长期令牌（只保存摘要）
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index:idx_personal_access_tokens_user_id" json:"user_id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	TokenHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"` // 令牌开头几位，便于用户辨认
+	Scopes     StringList `gorm:"type:jsonb;not null" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `gorm:"type:varchar(45)" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (t *PersonalAccessToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// Allows 令牌是否拥有访问指定资源的权限：读请求需要 <资源>:read，写请求需要 <资源>:write
+func (t *PersonalAccessToken) Allows(resource string, write bool) bool {
+	if write {
+		return t.Scopes.Contains(resource + ":write")
+	}
+	return t.Scopes.Contains(resource + ":read")
+}
+
+// IsActive 令牌是否可用
+func (t *PersonalAccessToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
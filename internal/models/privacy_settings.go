@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 个人主页可见范围
+const (
+	ProfileVisibilityPublic    = "public"    // 所有人可见
+	ProfileVisibilityFollowers = "followers" // 仅关注了自己的用户可见
+	ProfileVisibilityPrivate   = "private"   // 仅自己可见
+)
+
+// 允许评论自己帖子的范围
+const (
+	CommentPermissionEveryone  = "everyone"
+	CommentPermissionFollowers = "followers"
+	CommentPermissionNobody    = "nobody"
+)
+
+// PrivacySettings 用户隐私设置，没有记录时按默认值（全部公开）处理
+type PrivacySettings struct {
+	ID                uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"-"`
+	UserID            uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	ProfileVisibility string    `gorm:"type:varchar(20);not null;default:'public'" json:"profile_visibility"`
+	HideTrainingStats bool      `gorm:"not null;default:false" json:"hide_training_stats"` // 他人查看主页时隐藏训练天数、时长、周活跃度
+	HideFromPartners  bool      `gorm:"not null;default:false" json:"hide_from_partners"`  // 不出现在学习伙伴列表和 1v1 匹配中
+	CommentPermission string    `gorm:"type:varchar(20);not null;default:'everyone'" json:"comment_permission"`
+	CreatedAt         time.Time `json:"-"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (p *PrivacySettings) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// DefaultPrivacySettings 未设置过隐私选项的用户的默认设置
+func DefaultPrivacySettings(userID uuid.UUID) PrivacySettings {
+	return PrivacySettings{
+		UserID:            userID,
+		ProfileVisibility: ProfileVisibilityPublic,
+		CommentPermission: CommentPermissionEveryone,
+	}
+}
+
+// IsValidProfileVisibility 判断主页可见范围是否合法
+func IsValidProfileVisibility(v string) bool {
+	return v == ProfileVisibilityPublic || v == ProfileVisibilityFollowers || v == ProfileVisibilityPrivate
+}
+
+// IsValidCommentPermission 判断评论权限是否合法
+func IsValidCommentPermission(v string) bool {
+	return v == CommentPermissionEveryone || v == CommentPermissionFollowers || v == CommentPermissionNobody
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefreshToken 刷新令牌（只保存哈希）。同一会话（UserSession）中轮换产生的令牌构成一个令牌族
+type RefreshToken struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index:idx_refresh_tokens_user_id" json:"user_id"`
+	SessionID   uuid.UUID  `gorm:"type:uuid;not null;index:idx_refresh_tokens_session_id" json:"session_id"`
+	TokenHash   string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	DeviceName  string     `gorm:"type:varchar(100)" json:"device_name"`
+	RotatedFrom *uuid.UUID `gorm:"type:uuid" json:"rotated_from,omitempty"` // 由哪个令牌轮换而来
+	ExpiresAt   time.Time  `gorm:"not null;index:idx_refresh_tokens_expires_at" json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"` // 已轮换或已吊销
+	CreatedAt   time.Time  `json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (r *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
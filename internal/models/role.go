@@ -0,0 +1,66 @@
+package models
+
+// 用户角色
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+	RoleGuest     = "guest"     // 未绑定邮箱/手机号的游客账号，升级后变为 user
+	RoleTherapist = "therapist" // 言语治疗师，经客户同意后可查看其训练数据
+)
+
+// 权限
+const (
+	PermParticipateCommunity = "community:participate" // 发帖、评论、点赞、关注
+	PermDeleteAnyPost        = "community:delete_any_post"
+	PermDeleteAnyComment     = "community:delete_any_comment"
+	PermCloseAnyRoom         = "practice_room:close_any"
+	PermSuspendUser          = "user:suspend"
+	PermManageRoles          = "user:manage_roles"
+	PermViewAuditLog         = "audit:view"
+	PermViewClients          = "therapist:view_clients"
+)
+
+// RolePermissions 角色拥有的权限
+var RolePermissions = map[string][]string{
+	RoleGuest: {},
+	RoleUser: {
+		PermParticipateCommunity,
+	},
+	RoleTherapist: {
+		PermParticipateCommunity,
+		PermViewClients,
+	},
+	RoleModerator: {
+		PermParticipateCommunity,
+		PermDeleteAnyPost,
+		PermDeleteAnyComment,
+		PermCloseAnyRoom,
+		PermSuspendUser,
+	},
+	RoleAdmin: {
+		PermParticipateCommunity,
+		PermDeleteAnyPost,
+		PermDeleteAnyComment,
+		PermCloseAnyRoom,
+		PermSuspendUser,
+		PermManageRoles,
+		PermViewAuditLog,
+	},
+}
+
+// IsValidRole 判断角色是否存在
+func IsValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// HasPermission 判断角色是否拥有某项权限
+func HasPermission(role, permission string) bool {
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 客户可授权治疗师查看的数据范围
+const (
+	ConsentTrainingRecords    = "training_records"
+	ConsentMeditationProgress = "meditation_progress"
+	ConsentAIAnalysis         = "ai_analysis"
+)
+
+// ConsentScopes 全部可授权的数据范围
+var ConsentScopes = []string{ConsentTrainingRecords, ConsentMeditationProgress, ConsentAIAnalysis}
+
+// IsValidConsentScope 判断数据范围是否存在
+func IsValidConsentScope(scope string) bool {
+	for _, s := range ConsentScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// 治疗师与客户关联的状态
+const (
+	TherapistLinkPending = "pending" // 已发出邀请，等待客户同意
+	TherapistLinkActive  = "active"
+	TherapistLinkRevoked = "revoked" // 客户撤销授权或治疗师取消邀请
+)
+
+// TherapistLink 治疗师与客户的关联。治疗师发出邀请，客户接受并选择授权范围后生效，
+// 客户可以随时修改授权范围或撤销
+type TherapistLink struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TherapistID     uuid.UUID  `gorm:"type:uuid;not null;index:idx_therapist_links_therapist_id" json:"therapist_id"`
+	ClientID        *uuid.UUID `gorm:"type:uuid;index:idx_therapist_links_client_id" json:"client_id,omitempty"` // 客户接受邀请前为空
+	Status          string     `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Note            string     `gorm:"type:varchar(200)" json:"note,omitempty"` // 邀请附言
+	InviteTokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	InviteExpiresAt time.Time  `json:"invite_expires_at"`
+	RequestedScopes StringList `gorm:"type:jsonb;not null" json:"requested_scopes"` // 治疗师申请的范围
+	Scopes          StringList `gorm:"type:jsonb;not null" json:"scopes"`           // 客户实际授权的范围
+	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	Therapist *User `gorm:"foreignKey:TherapistID" json:"therapist,omitempty"`
+	Client    *User `gorm:"foreignKey:ClientID" json:"client,omitempty"`
+}
+
+func (l *TherapistLink) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+// Allows 客户是否授权了指定数据范围
+func (l *TherapistLink) Allows(scope string) bool {
+	return l.Status == TherapistLinkActive && l.Scopes.Contains(scope)
+}
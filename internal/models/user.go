@@ -17,6 +17,18 @@ type User struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	LockedUntil  *time.Time `json:"-"` // 连续登录失败后账号锁定到该时间
+	Role         string     `gorm:"type:varchar(20);not null;default:'user';index:idx_users_role" json:"role"` // 'guest' | 'user' | 'therapist' | 'moderator' | 'admin'
+	Timezone     string     `gorm:"type:varchar(64);not null;default:''" json:"timezone"`                       // IANA 时区（如 America/New_York），为空表示使用默认时区
+
+	// 封禁信息：SuspendedAt 不为空表示已封禁，SuspendedUntil 为空表示无限期
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason string     `gorm:"type:varchar(500)" json:"suspension_reason,omitempty"`
+
+	// 申请注销后，宽限期结束的时间（到期后由后台任务删除账号数据）
+	DeletionScheduledAt *time.Time `gorm:"index:idx_users_deletion_scheduled_at" json:"deletion_scheduled_at,omitempty"`
+
 	FollowersCount int `gorm:"default:0" json:"followers_count"` // 粉丝数量
 	FollowingCount int `gorm:"default:0" json:"following_count"` // 关注数量
 	IsFollowing    bool `gorm:"-" json:"is_following"`          // 是否关注了该用户 (瞬态字段)
@@ -26,9 +38,20 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == uuid.Nil {
 		u.ID = uuid.New()
 	}
+	if u.Role == "" {
+		u.Role = RoleUser
+	}
 	return nil
 }
 
+// IsSuspended 判断账号当前是否处于封禁状态
+func (u *User) IsSuspended(now time.Time) bool {
+	if u.SuspendedAt == nil {
+		return false
+	}
+	return u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil)
+}
+
 // UserProfile 包含用户基本信息和统计数据
 type UserAchievement struct {
 	ID              uuid.UUID `json:"id"`
@@ -47,6 +70,8 @@ type UserProfile struct {
 	TotalTrainingMinutes int             `json:"total_training_minutes"`
 	BraveryBadges       []UserAchievement `json:"bravery_badges"` // 假设 Achievement 是勋章模型
 	WeeklyActivity      []int           `json:"weekly_activity"`  // 例如，一周内每天的活跃度
+	ProfileRestricted   bool            `json:"profile_restricted"` // 主页对当前查看者不可见，只返回基本信息
+	StatsHidden         bool            `json:"stats_hidden"`       // 用户隐藏了训练统计
 }
 
 
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 屏蔽类型
+const (
+	BlockKindBlock = "block" // 拉黑：双方互相看不到内容，不能关注、评论、匹配，不能进入对方的房间
+	BlockKindMute  = "mute"  // 静音：只是自己看不到对方的帖子和评论
+)
+
+// UserBlock 用户拉黑/静音关系，每对用户最多一条（拉黑覆盖静音）
+type UserBlock struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BlockerID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_blocks_pair" json:"blocker_id"`
+	BlockedID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_user_blocks_pair;index:idx_user_blocks_blocked_id" json:"blocked_id"`
+	Kind      string    `gorm:"type:varchar(10);not null" json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Blocked *User `gorm:"foreignKey:BlockedID" json:"blocked,omitempty"`
+}
+
+func (b *UserBlock) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsValidBlockKind 判断屏蔽类型是否合法
+func IsValidBlockKind(kind string) bool {
+	return kind == BlockKindBlock || kind == BlockKindMute
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentity 第三方（OIDC）身份与本地用户的绑定关系
+type UserIdentity struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_user_identities_user_provider" json:"user_id"`
+	Provider    string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_user_identities_provider_subject;uniqueIndex:idx_user_identities_user_provider" json:"provider"`
+	Subject     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_provider_subject" json:"-"` // 提供方的 sub
+	Email       *string    `gorm:"type:varchar(255)" json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (i *UserIdentity) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// OAuthState 进行中的 OIDC 授权请求，保存 PKCE verifier 和 nonce，回调时一次性消费
+type OAuthState struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	State        string     `gorm:"type:varchar(100);not null;uniqueIndex" json:"-"`
+	Provider     string     `gorm:"type:varchar(50);not null" json:"provider"`
+	CodeVerifier string     `gorm:"type:varchar(128);not null" json:"-"`
+	Nonce        string     `gorm:"type:varchar(100);not null" json:"-"`
+	Purpose      string     `gorm:"type:varchar(20);not null" json:"purpose"`                          // 'login' | 'link'
+	UserID       *uuid.UUID `gorm:"type:uuid;index:idx_oauth_states_user_id" json:"user_id,omitempty"` // 绑定时的当前用户
+	ExpiresAt    time.Time  `gorm:"not null;index:idx_oauth_states_expires_at" json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (o *OAuthState) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
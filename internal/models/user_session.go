@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserSession 登录会话（设备）。同一会话下的刷新令牌共享 SessionID
+type UserSession struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index:idx_user_sessions_user_id" json:"user_id"`
+	DeviceName string     `gorm:"type:varchar(100)" json:"device_name"`
+	UserAgent  string     `gorm:"type:varchar(500)" json:"user_agent"`
+	IP         string     `gorm:"type:varchar(64)" json:"ip"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"` // 最新刷新令牌的过期时间
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	RevokedAt  *time.Time `gorm:"index:idx_user_sessions_revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	IsCurrent bool `gorm:"-" json:"is_current"` // 是否为发起请求的会话 (瞬态字段)
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (s *UserSession) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -10,11 +10,19 @@ import (
 type VerificationCode struct {
 	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	Identifier string    `gorm:"type:varchar(255);not null;index:idx_verification_codes_identifier" json:"identifier"`
-	Code       string    `gorm:"type:varchar(6);not null" json:"-"`
-	Type       string    `gorm:"type:varchar(20);not null" json:"type"` // 'register' | 'login'
+	Code       string    `gorm:"type:varchar(64);not null" json:"-"` // 验证码的 HMAC-SHA256 摘要，不保存明文
+	Type       string    `gorm:"type:varchar(20);not null" json:"type"` // 'register' | 'login' | 'reset_password'
+	IP         string    `gorm:"type:varchar(45);index:idx_verification_codes_ip" json:"-"`
+	Attempts   int       `gorm:"not null;default:0" json:"attempts"` // 校验次数，达到上限后该验证码作废
 	ExpiresAt  time.Time `gorm:"not null;index:idx_verification_codes_expires_at" json:"expires_at"`
 	Used       bool      `gorm:"default:false" json:"used"`
-	CreatedAt  time.Time `json:"created_at"`
+	CreatedAt  time.Time `gorm:"index:idx_verification_codes_created_at" json:"created_at"`
+
+	// 投递状态
+	DeliveryStatus   string     `gorm:"type:varchar(20);not null;default:'pending'" json:"delivery_status"` // 'pending' | 'sent' | 'failed' | 'skipped'
+	DeliveryAttempts int        `gorm:"not null;default:0" json:"delivery_attempts"`
+	DeliveryError    string     `gorm:"type:text" json:"delivery_error,omitempty"`
+	DeliveredAt      *time.Time `json:"delivered_at,omitempty"`
 }
 
 func (v *VerificationCode) BeforeCreate(tx *gorm.DB) error {
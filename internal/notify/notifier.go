@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"fluent-life-backend/internal/config"
+)
+
+// Message 一条待发送的通知（邮件或短信）
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject,omitempty"` // 短信忽略该字段
+	Body    string `json:"body"`
+}
+
+// Notifier 通知发送渠道
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// NewEmailNotifier 根据 EMAIL_PROVIDER 创建邮件渠道，未配置时返回 nil
+func NewEmailNotifier(cfg *config.Config) (Notifier, error) {
+	switch cfg.EmailProvider {
+	case "":
+		return nil, nil
+	case "smtp":
+		return NewSMTPNotifier(cfg.Notify.SMTPHost, cfg.Notify.SMTPPort, cfg.Notify.SMTPUsername, cfg.Notify.SMTPPassword, cfg.Notify.SMTPFrom), nil
+	case "outbox":
+		return NewOutboxNotifier(cfg.Notify.OutboxPath, "email"), nil
+	default:
+		return nil, fmt.Errorf("不支持的邮件服务: %s", cfg.EmailProvider)
+	}
+}
+
+// NewSMSNotifier 根据 SMS_PROVIDER 创建短信渠道，未配置时返回 nil
+func NewSMSNotifier(cfg *config.Config) (Notifier, error) {
+	switch cfg.SMSProvider {
+	case "":
+		return nil, nil
+	case "http":
+		return NewHTTPSMSNotifier(cfg.Notify.SMSEndpoint, cfg.Notify.SMSAPIKey, cfg.Notify.SMSSender), nil
+	case "outbox":
+		return NewOutboxNotifier(cfg.Notify.OutboxPath, "sms"), nil
+	default:
+		return nil, fmt.Errorf("不支持的短信服务: %s", cfg.SMSProvider)
+	}
+}
+
+// SendWithRetry 发送通知，失败时按指数退避重试，返回实际尝试次数
+func SendWithRetry(n Notifier, msg Message, maxAttempts int, backoff time.Duration) (int, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = n.Send(msg); err == nil {
+			return attempt, nil
+		}
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return maxAttempts, err
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var outboxMutex sync.Mutex
+
+// OutboxNotifier 把通知追加写入本地文件（每行一条 JSON），用于本地开发和测试
+type OutboxNotifier struct {
+	path    string
+	channel string
+}
+
+func NewOutboxNotifier(path, channel string) *OutboxNotifier {
+	return &OutboxNotifier{path: path, channel: channel}
+}
+
+func (n *OutboxNotifier) Send(msg Message) error {
+	line, err := json.Marshal(struct {
+		Channel string    `json:"channel"`
+		SentAt  time.Time `json:"sent_at"`
+		Message
+	}{Channel: n.channel, SentAt: time.Now(), Message: msg})
+	if err != nil {
+		return err
+	}
+
+	outboxMutex.Lock()
+	defer outboxMutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPSMSNotifier 通过 HTTP 网关发送短信。
+// 请求体为 {"to": "...", "sender": "...", "message": "..."}，返回 2xx 视为成功
+type HTTPSMSNotifier struct {
+	endpoint string
+	apiKey   string
+	sender   string
+	client   *http.Client
+}
+
+func NewHTTPSMSNotifier(endpoint, apiKey, sender string) *HTTPSMSNotifier {
+	return &HTTPSMSNotifier{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		sender:   sender,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *HTTPSMSNotifier) Send(msg Message) error {
+	if n.endpoint == "" {
+		return fmt.Errorf("短信网关地址未配置")
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"to":      msg.To,
+		"sender":  n.sender,
+		"message": msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", n.endpoint, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+n.apiKey)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("短信网关返回 %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
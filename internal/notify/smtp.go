@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier 通过 SMTP 发送邮件
+type SMTPNotifier struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPNotifier(host, port, username, password, from string) *SMTPNotifier {
+	return &SMTPNotifier{host: host, port: port, username: username, password: password, from: from}
+}
+
+func (n *SMTPNotifier) Send(msg Message) error {
+	if n.host == "" {
+		return fmt.Errorf("SMTP 服务器未配置")
+	}
+
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+
+	headers := []string{
+		"From: " + n.from,
+		"To: " + msg.To,
+		"Subject: " + mime.QEncoding.Encode("UTF-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + msg.Body
+
+	return smtp.SendMail(n.host+":"+n.port, auth, n.from, []string{msg.To}, []byte(body))
+}
@@ -0,0 +1,96 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownKey = errors.New("未知的签名密钥")
+
+// JWK 单个 JSON Web Key（只支持 RSA）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet 按 kid 索引的 RSA 公钥
+type KeySet struct {
+	keys map[string]*rsa.PublicKey
+}
+
+// KeySet 解析其中的 RSA 签名公钥
+func (s JWKS) KeySet() (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*rsa.PublicKey)}
+	for _, k := range s.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pub, err := k.RSAPublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("解析 JWK %s 失败: %w", k.Kid, err)
+		}
+		set.keys[k.Kid] = pub
+	}
+	if len(set.keys) == 0 {
+		return nil, errors.New("JWKS 中没有可用的 RSA 签名密钥")
+	}
+	return set, nil
+}
+
+// RSAPublicKey 由 n、e 还原 RSA 公钥
+func (k JWK) RSAPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("RSA 指数无效")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// NewRSAJWK 由 RSA 公钥生成 JWK
+func NewRSAJWK(kid string, pub *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+// Keyfunc 供 jwt.Parse 使用，按令牌头中的 kid 选择公钥
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, nil
+		}
+	}
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
@@ -0,0 +1,236 @@
+// Package oidc 实现 OpenID Connect 授权码 + PKCE 登录（仅依赖标准库和 jwt）
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config 单个身份提供方的配置
+type Config struct {
+	Name         string   `mapstructure:"name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+// Discovery OIDC 发现文档中用到的字段
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint,omitempty"`
+}
+
+// TokenResponse 令牌端点响应
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// IDTokenClaims ID Token 中用到的声明
+type IDTokenClaims struct {
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     bool   `json:"email_verified,omitempty"`
+	Name              string `json:"name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// discoveryTTL 发现文档和 JWKS 的缓存时间
+const discoveryTTL = time.Hour
+
+// Provider 身份提供方，发现文档和签名公钥按需拉取并缓存
+type Provider struct {
+	Config
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *Discovery
+	keys        *KeySet
+	refreshedAt time.Time
+}
+
+func NewProvider(cfg Config) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	return &Provider{
+		Config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// metadata 返回发现文档和公钥集合，过期后重新拉取
+func (p *Provider) metadata(forceRefresh bool) (*Discovery, *KeySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !forceRefresh && p.discovery != nil && time.Since(p.refreshedAt) < discoveryTTL {
+		return p.discovery, p.keys, nil
+	}
+
+	var discovery Discovery
+	wellKnown := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(wellKnown, &discovery); err != nil {
+		return nil, nil, fmt.Errorf("获取 OIDC 发现文档失败: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(p.Issuer, "/") {
+		return nil, nil, fmt.Errorf("发现文档 issuer 不匹配: %s", discovery.Issuer)
+	}
+
+	var jwks JWKS
+	if err := p.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, nil, fmt.Errorf("获取 JWKS 失败: %w", err)
+	}
+	keys, err := jwks.KeySet()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.discovery = &discovery
+	p.keys = keys
+	p.refreshedAt = time.Now()
+	return p.discovery, p.keys, nil
+}
+
+func (p *Provider) getJSON(endpoint string, v interface{}) error {
+	resp, err := p.client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回状态码 %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// AuthCodeURL 构造授权地址（授权码模式 + PKCE S256）
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	discovery, _, err := p.metadata(false)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientID)
+	params.Set("redirect_uri", p.RedirectURL)
+	params.Set("scope", strings.Join(p.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange 用授权码和 PKCE verifier 换取令牌
+func (p *Provider) Exchange(code, codeVerifier string) (*TokenResponse, error) {
+	discovery, _, err := p.metadata(false)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token TokenResponse
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&oauthErr)
+		return nil, fmt.Errorf("令牌端点返回 %d: %s %s", resp.StatusCode, oauthErr.Error, oauthErr.ErrorDescription)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("令牌响应中缺少 id_token")
+	}
+	return &token, nil
+}
+
+// VerifyIDToken 校验 ID Token 的签名、issuer、audience、有效期和 nonce
+func (p *Provider) VerifyIDToken(rawIDToken, nonce string) (*IDTokenClaims, error) {
+	claims, err := p.parseIDToken(rawIDToken, false)
+	if err != nil {
+		// 提供方可能轮换了签名密钥，强制刷新后重试一次
+		if !errors.Is(err, ErrUnknownKey) {
+			return nil, err
+		}
+		if claims, err = p.parseIDToken(rawIDToken, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("id_token 缺少 sub")
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("id_token nonce 不匹配")
+	}
+	return claims, nil
+}
+
+func (p *Provider) parseIDToken(rawIDToken string, forceRefresh bool) (*IDTokenClaims, error) {
+	discovery, keys, err := p.metadata(forceRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, keys.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		if errors.Is(err, ErrUnknownKey) {
+			return nil, ErrUnknownKey
+		}
+		return nil, fmt.Errorf("id_token 校验失败: %w", err)
+	}
+	return claims, nil
+}
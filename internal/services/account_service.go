@@ -0,0 +1,354 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDeletionAlreadyRequested = errors.New("账号已在注销流程中")
+	ErrDeletionNotRequested     = errors.New("账号未申请注销")
+)
+
+// UserDataExport 用户个人数据导出
+type UserDataExport struct {
+	ExportedAt         time.Time                   `json:"exported_at"`
+	User               models.User                 `json:"user"`
+	TrainingRecords    []models.TrainingRecord     `json:"training_records"`
+	MeditationProgress []models.MeditationProgress `json:"meditation_progress"`
+	AIConversation     *models.AIConversation      `json:"ai_conversation,omitempty"`
+	Posts              []models.Post               `json:"posts"`
+	Comments           []models.Comment            `json:"comments"`
+	PostLikes          []models.PostLike           `json:"post_likes"`
+	Following          []models.Follow             `json:"following"`
+	Followers          []models.Follow             `json:"followers"`
+	Collections        []models.PostCollection     `json:"collections"`
+	Achievements       []models.Achievement        `json:"achievements"`
+	Identities         []models.UserIdentity       `json:"identities"`
+	PrivacySettings    *models.PrivacySettings     `json:"privacy_settings,omitempty"`
+	Blocks             []models.UserBlock          `json:"blocks"`
+	IdentifierChanges  []models.IdentifierChange   `json:"identifier_changes"`
+	TherapistLinks     []models.TherapistLink      `json:"therapist_links"`
+	Assignments        []models.Assignment         `json:"assignments"`
+	Notifications      []models.Notification       `json:"notifications"`
+	ClinicalNotes      []models.ClinicalNote       `json:"clinical_notes"`
+	Goals              []models.Goal               `json:"goals"`
+	GoalPeriods        []models.GoalPeriod         `json:"goal_periods"`
+}
+
+// AccountService 账号数据导出与注销
+type AccountService struct {
+	db           *gorm.DB
+	cfg          *config.Config
+	tokenService *TokenService
+	audit        *AuditService
+}
+
+func NewAccountService(db *gorm.DB, cfg *config.Config) *AccountService {
+	return &AccountService{
+		db:           db,
+		cfg:          cfg,
+		tokenService: NewTokenService(db, cfg),
+		audit:        NewAuditService(db),
+	}
+}
+
+// ExportData 汇总用户的全部个人数据
+func (s *AccountService) ExportData(userID uuid.UUID) (*UserDataExport, error) {
+	export := &UserDataExport{ExportedAt: time.Now()}
+
+	if err := s.db.First(&export.User, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		dest  interface{}
+		query *gorm.DB
+	}{
+		{&export.TrainingRecords, s.db.Where("user_id = ?", userID).Order("timestamp ASC")},
+		{&export.MeditationProgress, s.db.Where("user_id = ?", userID).Order("stage ASC")},
+		{&export.Posts, s.db.Where("user_id = ?", userID).Order("created_at ASC")},
+		{&export.Comments, s.db.Where("user_id = ?", userID).Order("created_at ASC")},
+		{&export.PostLikes, s.db.Where("user_id = ?", userID).Order("created_at ASC")},
+		{&export.Following, s.db.Preload("Followee").Where("follower_id = ?", userID).Order("created_at ASC")},
+		{&export.Followers, s.db.Preload("Follower").Where("followee_id = ?", userID).Order("created_at ASC")},
+		{&export.Collections, s.db.Where("user_id = ?", userID).Order("created_at ASC")},
+		{&export.Achievements, s.db.Where("user_id = ?", userID).Order("unlocked_at ASC")},
+		{&export.Identities, s.db.Where("user_id = ?", userID).Order("created_at ASC")},
+		{&export.Blocks, s.db.Where("blocker_id = ?", userID).Order("created_at ASC")},
+		{&export.IdentifierChanges, s.db.Where("user_id = ?", userID).Order("created_at ASC")},
+		{&export.TherapistLinks, s.db.Where("client_id = ? OR therapist_id = ?", userID, userID).Order("created_at ASC")},
+		{&export.Assignments, s.db.Where("client_id = ? OR therapist_id = ?", userID, userID).Order("created_at ASC")},
+		{&export.Notifications, s.db.Where("user_id = ?", userID).Order("created_at ASC")},
+		// 自己撰写的记录，以及治疗师分享给自己的记录
+		{&export.ClinicalNotes, s.db.Where("deleted_at IS NULL AND (therapist_id = ? OR (client_id = ? AND shared_with_client = ?))", userID, userID, true).Order("session_date ASC")},
+		{&export.Goals, s.db.Where("user_id = ?", userID).Order("created_at ASC")},
+		{&export.GoalPeriods, s.db.Where("user_id = ?", userID).Order("period_start ASC")},
+	}
+	for _, q := range queries {
+		if err := q.query.Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var conversation models.AIConversation
+	if err := s.db.Where("user_id = ?", userID).First(&conversation).Error; err == nil {
+		export.AIConversation = &conversation
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var privacy models.PrivacySettings
+	if err := s.db.Where("user_id = ?", userID).First(&privacy).Error; err == nil {
+		export.PrivacySettings = &privacy
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// WriteExportZip 将导出数据按类别写成多个 JSON 文件并打包为 ZIP
+func (s *AccountService) WriteExportZip(w io.Writer, export *UserDataExport) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"user.json", export.User},
+		{"training_records.json", export.TrainingRecords},
+		{"meditation_progress.json", export.MeditationProgress},
+		{"ai_conversation.json", export.AIConversation},
+		{"posts.json", export.Posts},
+		{"comments.json", export.Comments},
+		{"post_likes.json", export.PostLikes},
+		{"following.json", export.Following},
+		{"followers.json", export.Followers},
+		{"collections.json", export.Collections},
+		{"achievements.json", export.Achievements},
+		{"identities.json", export.Identities},
+		{"privacy_settings.json", export.PrivacySettings},
+		{"blocks.json", export.Blocks},
+		{"identifier_changes.json", export.IdentifierChanges},
+		{"therapist_links.json", export.TherapistLinks},
+		{"assignments.json", export.Assignments},
+		{"notifications.json", export.Notifications},
+		{"clinical_notes.json", export.ClinicalNotes},
+		{"goals.json", export.Goals},
+		{"goal_periods.json", export.GoalPeriods},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// RequestDeletion 申请注销账号，宽限期结束后删除数据；设置过密码的账号需要验证密码
+func (s *AccountService) RequestDeletion(userID uuid.UUID, password string) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	if user.DeletionScheduledAt != nil {
+		return nil, ErrDeletionAlreadyRequested
+	}
+	if user.PasswordHash != "" {
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+			return nil, errors.New("密码错误")
+		}
+	}
+
+	scheduledAt := time.Now().Add(s.cfg.AccountDeletionGracePeriod)
+	if err := s.db.Model(&user).Update("deletion_scheduled_at", scheduledAt).Error; err != nil {
+		return nil, err
+	}
+	user.DeletionScheduledAt = &scheduledAt
+
+	utils.APILog("[AccountService.RequestDeletion] 用户 %s 申请注销账号，将于 %s 删除", userID, scheduledAt.Format(time.RFC3339))
+	return &user, nil
+}
+
+// CancelDeletion 在宽限期内撤销注销申请
+func (s *AccountService) CancelDeletion(userID uuid.UUID) error {
+	result := s.db.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userID).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeletionNotRequested
+	}
+
+	utils.APILog("[AccountService.CancelDeletion] 用户 %s 撤销了注销申请", userID)
+	return nil
+}
+
+// ProcessDueDeletions 删除宽限期已结束的账号
+func (s *AccountService) ProcessDueDeletions() error {
+	var userIDs []uuid.UUID
+	if err := s.db.Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).
+		Pluck("id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := s.deleteUser(userID); err != nil {
+			utils.APILog("[AccountService.ProcessDueDeletions] ❌ 删除用户 %s 失败: %v", userID, err)
+			continue
+		}
+		utils.APILog("[AccountService.ProcessDueDeletions] 用户 %s 的账号及数据已删除", userID)
+		s.audit.Record(DeviceInfo{}, models.AuditEvent{
+			Action:     models.AuditAccountDeleted,
+			TargetType: models.AuditTargetUser,
+			TargetID:   userID.String(),
+		})
+	}
+	return nil
+}
+
+// deleteUser 删除用户及其全部数据，同时修正其他用户、帖子、评论上的计数
+func (s *AccountService) deleteUser(userID uuid.UUID) error {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		community := NewCommunityService(tx)
+
+		// 自己的帖子连同其下的评论、点赞、收藏一起删除
+		var posts []models.Post
+		if err := tx.Where("user_id = ?", userID).Find(&posts).Error; err != nil {
+			return err
+		}
+		for _, post := range posts {
+			if err := community.removePost(post); err != nil {
+				return err
+			}
+		}
+
+		// 在他人帖子下的评论
+		if err := tx.Exec(`UPDATE posts SET comments_count = GREATEST(posts.comments_count - c.cnt, 0)
+			FROM (SELECT post_id, COUNT(*) AS cnt FROM comments WHERE user_id = ? GROUP BY post_id) c
+			WHERE posts.id = c.post_id`, userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id IN (?)", tx.Model(&models.Comment{}).Select("id").Where("user_id = ?", userID)).
+			Delete(&models.CommentLike{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+
+		// 点赞、收藏
+		counterFixes := []string{
+			`UPDATE posts SET likes_count = GREATEST(likes_count - 1, 0) WHERE id IN (SELECT post_id FROM post_likes WHERE user_id = ?)`,
+			`UPDATE comments SET likes_count = GREATEST(likes_count - 1, 0) WHERE id IN (SELECT comment_id FROM comment_likes WHERE user_id = ?)`,
+			`UPDATE posts SET favorites_count = GREATEST(favorites_count - 1, 0) WHERE id IN (SELECT post_id FROM post_collections WHERE user_id = ?)`,
+			`UPDATE users SET followers_count = GREATEST(followers_count - 1, 0) WHERE id IN (SELECT followee_id FROM follows WHERE follower_id = ?)`,
+			`UPDATE users SET following_count = GREATEST(following_count - 1, 0) WHERE id IN (SELECT follower_id FROM follows WHERE followee_id = ?)`,
+		}
+		for _, sql := range counterFixes {
+			if err := tx.Exec(sql, userID).Error; err != nil {
+				return err
+			}
+		}
+
+		// 自己创建的对练房
+		roomIDs := tx.Model(&models.PracticeRoom{}).Select("id").Where("user_id = ?", userID)
+		if err := tx.Where("room_id IN (?)", roomIDs).Delete(&models.PracticeRoomMember{}).Error; err != nil {
+			return err
+		}
+
+		// 作为治疗师撰写或作为客户被记录的临床记录及其历史版本
+		noteIDs := tx.Model(&models.ClinicalNote{}).Select("id").Where("therapist_id = ? OR client_id = ?", userID, userID)
+		if err := tx.Where("note_id IN (?)", noteIDs).Delete(&models.ClinicalNoteVersion{}).Error; err != nil {
+			return err
+		}
+
+		owned := []struct {
+			model  interface{}
+			column string
+		}{
+			{&models.PostLike{}, "user_id"},
+			{&models.CommentLike{}, "user_id"},
+			{&models.PostCollection{}, "user_id"},
+			{&models.Follow{}, "follower_id"},
+			{&models.Follow{}, "followee_id"},
+			{&models.PracticeRoomMember{}, "user_id"},
+			{&models.PracticeRoom{}, "user_id"},
+			{&models.TrainingRecord{}, "user_id"},
+			{&models.MeditationProgress{}, "user_id"},
+			{&models.AIConversation{}, "user_id"},
+			{&models.Achievement{}, "user_id"},
+			{&models.RefreshToken{}, "user_id"},
+			{&models.UserSession{}, "user_id"},
+			{&models.UserIdentity{}, "user_id"},
+			{&models.OAuthState{}, "user_id"},
+			{&models.TwoFactor{}, "user_id"},
+			{&models.RecoveryCode{}, "user_id"},
+			{&models.PersonalAccessToken{}, "user_id"},
+			{&models.PrivacySettings{}, "user_id"},
+			{&models.UserBlock{}, "blocker_id"},
+			{&models.UserBlock{}, "blocked_id"},
+			{&models.IdentifierChange{}, "user_id"},
+			{&models.TherapistLink{}, "therapist_id"},
+			{&models.TherapistLink{}, "client_id"},
+			{&models.Assignment{}, "therapist_id"},
+			{&models.Assignment{}, "client_id"},
+			{&models.Notification{}, "user_id"},
+			{&models.ClinicalNote{}, "therapist_id"},
+			{&models.ClinicalNote{}, "client_id"},
+			{&models.GoalPeriod{}, "user_id"},
+			{&models.Goal{}, "user_id"},
+		}
+		for _, o := range owned {
+			if err := tx.Where(o.column+" = ?", userID).Delete(o.model).Error; err != nil {
+				return err
+			}
+		}
+
+		// 以邮箱/手机号为键的记录
+		var identifiers []string
+		if user.Email != nil {
+			identifiers = append(identifiers, *user.Email)
+		}
+		if user.Phone != nil {
+			identifiers = append(identifiers, *user.Phone)
+		}
+		if len(identifiers) > 0 {
+			if err := tx.Where("identifier IN ?", identifiers).Delete(&models.VerificationCode{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("scope = ? AND key IN ?", attemptScopeIdentifier, identifiers).Delete(&models.LoginAttempt{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&user).Error
+	})
+}
@@ -1,9 +1,13 @@
 package services
 
 import (
+	"time"
+
 	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AchievementService struct {
@@ -44,6 +48,46 @@ var achievementDefinitions = map[string]struct {
 		Icon:  "🔥",
 		Desc:  "完成社会挑战",
 	},
+	"streak_7": {
+		Title: "坚持一周",
+		Icon:  "📅",
+		Desc:  "连续练习7天",
+	},
+	"streak_30": {
+		Title: "月度坚持",
+		Icon:  "🌙",
+		Desc:  "连续练习30天",
+	},
+	"streak_100": {
+		Title: "百日坚持",
+		Icon:  "💯",
+		Desc:  "连续练习100天",
+	},
+	"goal_first": {
+		Title: "目标达成",
+		Icon:  "🎯",
+		Desc:  "第一次达成练习目标",
+	},
+	"goal_30": {
+		Title: "言出必行",
+		Icon:  "🏆",
+		Desc:  "累计达成练习目标30次",
+	},
+}
+
+// Unlock 解锁成就，已解锁时不重复创建。返回是否为本次新解锁
+func (s *AchievementService) Unlock(userID uuid.UUID, achievementType string) bool {
+	achievement := models.Achievement{
+		UserID:          userID,
+		AchievementType: achievementType,
+		UnlockedAt:      time.Now(),
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&achievement)
+	if result.Error != nil {
+		utils.APILog("[AchievementService.Unlock] ❌ 用户 %s 解锁成就 %s 失败: %v", userID, achievementType, result.Error)
+		return false
+	}
+	return result.RowsAffected > 0
 }
 
 func (s *AchievementService) GetAchievements(userID uuid.UUID) ([]AchievementInfo, error) {
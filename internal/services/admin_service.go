@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInsufficientRank = errors.New("无权对该用户执行此操作")
+	ErrInvalidRole      = errors.New("无效的角色")
+)
+
+// roleRank 角色等级，只能管理等级低于自己的用户
+var roleRank = map[string]int{
+	models.RoleGuest:     0,
+	models.RoleUser:      0,
+	models.RoleTherapist: 0,
+	models.RoleModerator: 1,
+	models.RoleAdmin:     2,
+}
+
+// AdminService 社区管理与用户管理
+type AdminService struct {
+	db                  *gorm.DB
+	tokenService        *TokenService
+	communityService    *CommunityService
+	practiceRoomService *PracticeRoomService
+	audit               *AuditService
+}
+
+func NewAdminService(db *gorm.DB, cfg *config.Config) *AdminService {
+	return &AdminService{
+		db:                  db,
+		tokenService:        NewTokenService(db, cfg),
+		communityService:    NewCommunityService(db),
+		practiceRoomService: NewPracticeRoomService(db),
+		audit:               NewAuditService(db),
+	}
+}
+
+// DeletePost 删除任意帖子
+func (s *AdminService) DeletePost(actorID, postID uuid.UUID, client DeviceInfo) error {
+	post, err := s.communityService.ModerateDeletePost(postID)
+	if err != nil {
+		return err
+	}
+	s.audit.Record(client, models.AuditEvent{
+		ActorID:    &actorID,
+		Action:     models.AuditAdminPostDelete,
+		TargetType: models.AuditTargetPost,
+		TargetID:   postID.String(),
+		Metadata:   models.JSONB{"owner_id": post.UserID},
+	})
+	return nil
+}
+
+// DeleteComment 删除任意评论
+func (s *AdminService) DeleteComment(actorID, commentID uuid.UUID, client DeviceInfo) error {
+	comment, err := s.communityService.ModerateDeleteComment(commentID)
+	if err != nil {
+		return err
+	}
+	s.audit.Record(client, models.AuditEvent{
+		ActorID:    &actorID,
+		Action:     models.AuditAdminCommentDelete,
+		TargetType: models.AuditTargetComment,
+		TargetID:   commentID.String(),
+		Metadata:   models.JSONB{"owner_id": comment.UserID, "post_id": comment.PostID},
+	})
+	return nil
+}
+
+// CloseRoom 强制关闭对练房
+func (s *AdminService) CloseRoom(actorID, roomID uuid.UUID, client DeviceInfo) error {
+	room, err := s.practiceRoomService.CloseRoom(roomID)
+	if err != nil {
+		return err
+	}
+	s.audit.Record(client, models.AuditEvent{
+		ActorID:    &actorID,
+		Action:     models.AuditAdminRoomClose,
+		TargetType: models.AuditTargetRoom,
+		TargetID:   roomID.String(),
+		Metadata:   models.JSONB{"owner_id": room.UserID},
+	})
+	return nil
+}
+
+// SuspendUser 封禁用户并吊销其全部登录会话；until 为空表示无限期
+func (s *AdminService) SuspendUser(actorID, userID uuid.UUID, until *time.Time, reason string, client DeviceInfo) (*models.User, error) {
+	target, err := s.manageableUser(actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.db.Model(target).Updates(map[string]interface{}{
+		"suspended_at":      now,
+		"suspended_until":   until,
+		"suspension_reason": reason,
+	}).Error; err != nil {
+		return nil, err
+	}
+	target.SuspendedAt = &now
+	target.SuspendedUntil = until
+	target.SuspensionReason = reason
+
+	if err := s.tokenService.RevokeAllSessions(userID); err != nil {
+		utils.APILog("[AdminService.SuspendUser] ⚠️ 吊销用户 %s 的会话失败: %v", userID, err)
+	}
+	s.audit.Record(client, models.AuditEvent{
+		ActorID:    &actorID,
+		Action:     models.AuditAdminUserSuspend,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID.String(),
+		Metadata:   models.JSONB{"reason": reason, "until": until},
+	})
+	return target, nil
+}
+
+// UnsuspendUser 解除封禁
+func (s *AdminService) UnsuspendUser(actorID, userID uuid.UUID, client DeviceInfo) (*models.User, error) {
+	target, err := s.manageableUser(actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(target).Updates(map[string]interface{}{
+		"suspended_at":      nil,
+		"suspended_until":   nil,
+		"suspension_reason": "",
+	}).Error; err != nil {
+		return nil, err
+	}
+	target.SuspendedAt = nil
+	target.SuspendedUntil = nil
+	target.SuspensionReason = ""
+
+	s.audit.Record(client, models.AuditEvent{
+		ActorID:    &actorID,
+		Action:     models.AuditAdminUserUnsuspend,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID.String(),
+	})
+	return target, nil
+}
+
+// SetRole 修改用户角色（游客角色只能由游客账号升级产生，不能手动设置）
+func (s *AdminService) SetRole(actorID, userID uuid.UUID, role string, client DeviceInfo) (*models.User, error) {
+	if !models.IsValidRole(role) || role == models.RoleGuest {
+		return nil, ErrInvalidRole
+	}
+	target, err := s.manageableUser(actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := target.Role
+	if err := s.db.Model(target).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	target.Role = role
+
+	s.audit.Record(client, models.AuditEvent{
+		ActorID:    &actorID,
+		Action:     models.AuditAdminRoleChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID.String(),
+		Metadata:   models.JSONB{"from": previous, "to": role},
+	})
+	return target, nil
+}
+
+// manageableUser 加载目标用户，并确认操作者等级高于目标用户（不能操作自己）
+func (s *AdminService) manageableUser(actorID, userID uuid.UUID) (*models.User, error) {
+	if actorID == userID {
+		return nil, ErrInsufficientRank
+	}
+
+	var actor, target models.User
+	if err := s.db.Select("id", "role").First(&actor, "id = ?", actorID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&target, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	if roleRank[actor.Role] <= roleRank[target.Role] {
+		return nil, ErrInsufficientRank
+	}
+	return &target, nil
+}
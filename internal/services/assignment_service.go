@@ -0,0 +1,250 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrAssignmentNotActive = errors.New("作业已完成、已过期或已取消")
+
+// trainingTypeNames 训练类型的中文名称（用于通知文案）
+var trainingTypeNames = map[string]string{
+	"meditation": "冥想",
+	"airflow":    "气流练习",
+	"exposure":   "脱敏训练",
+	"practice":   "实战练习",
+}
+
+// AssignmentService 治疗师布置的练习作业：布置、取消、自动/手动完成和逾期处理
+type AssignmentService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+}
+
+func NewAssignmentService(db *gorm.DB) *AssignmentService {
+	return &AssignmentService{
+		db:                  db,
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// AssignmentInput 治疗师布置作业时填写的内容
+type AssignmentInput struct {
+	Type        string
+	TargetCount int
+	MinDuration int // 秒
+	Notes       string
+	StartsAt    *time.Time // 为空表示从现在开始
+	DueAt       time.Time
+}
+
+// Create 治疗师给已关联的客户布置作业
+func (s *AssignmentService) Create(therapistID, clientID uuid.UUID, input AssignmentInput) (*models.Assignment, error) {
+	if _, err := findActiveLink(s.db, therapistID, clientID); err != nil {
+		return nil, err
+	}
+	if _, ok := trainingTypeNames[input.Type]; !ok {
+		return nil, errors.New("无效的训练类型")
+	}
+	if input.TargetCount <= 0 {
+		input.TargetCount = 1
+	}
+	if input.TargetCount > 100 || input.MinDuration < 0 {
+		return nil, errors.New("目标次数或时长不合法")
+	}
+
+	startsAt := time.Now()
+	if input.StartsAt != nil {
+		startsAt = *input.StartsAt
+	}
+	if !input.DueAt.After(startsAt) || !input.DueAt.After(time.Now()) {
+		return nil, errors.New("截止时间必须晚于开始时间和当前时间")
+	}
+
+	assignment := models.Assignment{
+		TherapistID: therapistID,
+		ClientID:    clientID,
+		Type:        input.Type,
+		TargetCount: input.TargetCount,
+		MinDuration: input.MinDuration,
+		Notes:       strings.TrimSpace(input.Notes),
+		StartsAt:    startsAt,
+		DueAt:       input.DueAt,
+		Status:      models.AssignmentActive,
+	}
+	if err := s.db.Create(&assignment).Error; err != nil {
+		return nil, err
+	}
+
+	utils.APILog("[AssignmentService.Create] 治疗师 %s 给客户 %s 布置了作业 %s（%s × %d）", therapistID, clientID, assignment.ID, assignment.Type, assignment.TargetCount)
+	return &assignment, nil
+}
+
+// ListForTherapist 治疗师给某个客户布置的作业，status 为空表示全部
+func (s *AssignmentService) ListForTherapist(therapistID, clientID uuid.UUID, status string, page, pageSize int) ([]models.Assignment, int64, error) {
+	if _, err := findActiveLink(s.db, therapistID, clientID); err != nil {
+		return nil, 0, err
+	}
+	return s.list(s.db.Where("therapist_id = ? AND client_id = ?", therapistID, clientID), status, "Client", page, pageSize)
+}
+
+// ListForClient 客户收到的作业，status 为空表示全部
+func (s *AssignmentService) ListForClient(clientID uuid.UUID, status string, page, pageSize int) ([]models.Assignment, int64, error) {
+	return s.list(s.db.Where("client_id = ?", clientID), status, "Therapist", page, pageSize)
+}
+
+func (s *AssignmentService) list(query *gorm.DB, status, preload string, page, pageSize int) ([]models.Assignment, int64, error) {
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Model(&models.Assignment{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var assignments []models.Assignment
+	err := query.Preload(preload, preloadBasicUser).
+		Order("due_at ASC").Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&assignments).Error
+	return assignments, total, err
+}
+
+// Cancel 治疗师取消尚未完成的作业
+func (s *AssignmentService) Cancel(therapistID, assignmentID uuid.UUID) error {
+	result := s.db.Model(&models.Assignment{}).
+		Where("id = ? AND therapist_id = ? AND status = ?", assignmentID, therapistID, models.AssignmentActive).
+		Update("status", models.AssignmentCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Complete 客户手动将作业标记为完成（例如线下完成的脱敏任务）
+func (s *AssignmentService) Complete(clientID, assignmentID uuid.UUID) (*models.Assignment, error) {
+	var assignment models.Assignment
+	if err := s.db.Where("id = ? AND client_id = ?", assignmentID, clientID).First(&assignment).Error; err != nil {
+		return nil, err
+	}
+	if assignment.Status != models.AssignmentActive {
+		return nil, ErrAssignmentNotActive
+	}
+	if !s.markCompleted(&assignment) {
+		return nil, ErrAssignmentNotActive
+	}
+	return &assignment, nil
+}
+
+// markCompleted 将进行中的作业标记为完成并通知治疗师，作业已不在进行中时返回 false
+func (s *AssignmentService) markCompleted(assignment *models.Assignment) bool {
+	now := time.Now()
+	result := s.db.Model(&models.Assignment{}).
+		Where("id = ? AND status = ?", assignment.ID, models.AssignmentActive).
+		Updates(map[string]interface{}{"status": models.AssignmentCompleted, "completed_at": now})
+	if result.Error != nil {
+		utils.APILog("[AssignmentService.markCompleted] ❌ 作业 %s 标记完成失败: %v", assignment.ID, result.Error)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+	assignment.Status = models.AssignmentCompleted
+	assignment.CompletedAt = &now
+
+	s.notifyTherapist(assignment, models.NotificationAssignmentCompleted, "作业已完成",
+		fmt.Sprintf("%s 完成了%s作业（%d/%d 次）", s.clientName(assignment.ClientID), trainingTypeNames[assignment.Type], assignment.CompletedCount, assignment.TargetCount))
+	return true
+}
+
+// RecordProgress 客户记录训练后调用：同类型、在作业时间范围内且时长达标的记录计入完成次数，
+// 达到目标次数时自动完成作业。失败只记录日志，不影响训练记录的创建
+func (s *AssignmentService) RecordProgress(record *models.TrainingRecord) {
+	var assignments []models.Assignment
+	if err := s.db.Where("client_id = ? AND type = ? AND status = ? AND starts_at <= ? AND due_at >= ? AND min_duration <= ?",
+		record.UserID, record.Type, models.AssignmentActive, record.Timestamp, record.Timestamp, record.Duration).
+		Find(&assignments).Error; err != nil {
+		utils.APILog("[AssignmentService.RecordProgress] ❌ 查询用户 %s 的作业失败: %v", record.UserID, err)
+		return
+	}
+
+	for i := range assignments {
+		assignment := &assignments[i]
+		result := s.db.Model(&models.Assignment{}).
+			Where("id = ? AND status = ?", assignment.ID, models.AssignmentActive).
+			Update("completed_count", gorm.Expr("completed_count + 1"))
+		if result.Error != nil {
+			utils.APILog("[AssignmentService.RecordProgress] ❌ 更新作业 %s 进度失败: %v", assignment.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		// 重新读取完成次数，避免并发记录时基于旧值判断
+		if err := s.db.Model(&models.Assignment{}).Where("id = ?", assignment.ID).
+			Select("completed_count").Scan(&assignment.CompletedCount).Error; err != nil {
+			utils.APILog("[AssignmentService.RecordProgress] ❌ 读取作业 %s 进度失败: %v", assignment.ID, err)
+			continue
+		}
+		if assignment.CompletedCount >= assignment.TargetCount {
+			s.markCompleted(assignment)
+		}
+	}
+}
+
+// MarkOverdue 将已过截止时间仍未完成的作业标记为逾期并通知治疗师
+func (s *AssignmentService) MarkOverdue() error {
+	var assignments []models.Assignment
+	if err := s.db.Where("status = ? AND due_at < ?", models.AssignmentActive, time.Now()).Find(&assignments).Error; err != nil {
+		return err
+	}
+
+	for i := range assignments {
+		assignment := &assignments[i]
+		result := s.db.Model(&models.Assignment{}).
+			Where("id = ? AND status = ?", assignment.ID, models.AssignmentActive).
+			Update("status", models.AssignmentOverdue)
+		if result.Error != nil {
+			utils.APILog("[AssignmentService.MarkOverdue] ❌ 作业 %s 标记逾期失败: %v", assignment.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		assignment.Status = models.AssignmentOverdue
+		s.notifyTherapist(assignment, models.NotificationAssignmentOverdue, "作业已逾期",
+			fmt.Sprintf("%s 的%s作业已到截止时间，完成 %d/%d 次", s.clientName(assignment.ClientID), trainingTypeNames[assignment.Type], assignment.CompletedCount, assignment.TargetCount))
+	}
+	if len(assignments) > 0 {
+		utils.APILog("[AssignmentService.MarkOverdue] 已处理 %d 个逾期作业", len(assignments))
+	}
+	return nil
+}
+
+func (s *AssignmentService) clientName(clientID uuid.UUID) string {
+	var user models.User
+	if err := s.db.Select("username").First(&user, "id = ?", clientID).Error; err != nil {
+		return "客户"
+	}
+	return user.Username
+}
+
+func (s *AssignmentService) notifyTherapist(assignment *models.Assignment, notificationType, title, body string) {
+	s.notificationService.Notify(assignment.TherapistID, notificationType, title, body, models.JSONB{
+		"assignment_id":   assignment.ID.String(),
+		"client_id":       assignment.ClientID.String(),
+		"type":            assignment.Type,
+		"completed_count": assignment.CompletedCount,
+		"target_count":    assignment.TargetCount,
+	})
+}
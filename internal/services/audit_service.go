@@ -0,0 +1,96 @@
+package services
+
+import (
+	"time"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditFilter 审计事件查询条件，零值表示不过滤
+type AuditFilter struct {
+	ActorID    *uuid.UUID
+	Action     string // 完整事件类型，或以 . 结尾的前缀（如 "admin."）
+	TargetType string
+	TargetID   string
+	IP         string
+	Since      *time.Time
+	Until      *time.Time
+}
+
+// AuditService 安全审计日志
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// Record 写入一条审计事件。写入失败只记录日志，不影响业务流程
+func (s *AuditService) Record(client DeviceInfo, event models.AuditEvent) {
+	event.IP = client.IP
+	event.UserAgent = client.UserAgent
+	if len(event.UserAgent) > 500 {
+		event.UserAgent = event.UserAgent[:500]
+	}
+	if err := s.db.Create(&event).Error; err != nil {
+		utils.APILog("[AuditService.Record] ❌ 写入审计事件 %s 失败: %v", event.Action, err)
+	}
+}
+
+// List 按条件分页查询审计事件，按时间倒序
+func (s *AuditService) List(filter AuditFilter, page, pageSize int) ([]models.AuditEvent, int64, error) {
+	query := s.db.Model(&models.AuditEvent{})
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		if filter.Action[len(filter.Action)-1] == '.' {
+			query = query.Where("action LIKE ?", filter.Action+"%")
+		} else {
+			query = query.Where("action = ?", filter.Action)
+		}
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.AuditEvent
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// PurgeBefore 删除早于指定时间的审计事件（保留期清理）
+func (s *AuditService) PurgeBefore(cutoff time.Time) error {
+	result := s.db.Where("created_at < ?", cutoff).Delete(&models.AuditEvent{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		utils.APILog("[AuditService.PurgeBefore] 清理了 %d 条超过保留期的审计事件", result.RowsAffected)
+	}
+	return nil
+}
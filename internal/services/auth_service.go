@@ -3,21 +3,34 @@ package services
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"fluent-life-backend/internal/config"
 	"fluent-life-backend/internal/models"
-	"fluent-life-backend/pkg/auth"
+	"fluent-life-backend/internal/utils"
 	"fluent-life-backend/pkg/validator"
 
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// 登录方式（审计事件中的 method）
+const (
+	loginMethodPassword  = "password"
+	loginMethodCode      = "code"
+	loginMethodTwoFactor = "two_factor"
+)
+
 type AuthService struct {
 	db                      *gorm.DB
 	cfg                     *config.Config
 	verificationCodeService *VerificationCodeService
+	tokenService            *TokenService
+	loginThrottle           *LoginThrottleService
+	twoFactor               *TwoFactorService
+	audit                   *AuditService
 }
 
 func NewAuthService(db *gorm.DB, cfg *config.Config) *AuthService {
@@ -25,6 +38,10 @@ func NewAuthService(db *gorm.DB, cfg *config.Config) *AuthService {
 		db:                      db,
 		cfg:                     cfg,
 		verificationCodeService: NewVerificationCodeService(db, cfg),
+		tokenService:            NewTokenService(db, cfg),
+		loginThrottle:           NewLoginThrottleService(db, cfg),
+		twoFactor:               NewTwoFactorService(db, cfg),
+		audit:                   NewAuditService(db),
 	}
 }
 
@@ -41,43 +58,44 @@ func (s *AuthService) VerifyPassword(hashedPassword, password string) bool {
 	return err == nil
 }
 
-func (s *AuthService) Register(username, identifier, password, code string) (*models.User, string, error) {
+func (s *AuthService) Register(username, identifier, password, code string, device DeviceInfo) (*models.User, *TokenPair, error) {
 	// 验证输入
 	if !validator.IsEmailOrPhone(identifier) {
-		return nil, "", errors.New("邮箱或手机号格式不正确")
+		return nil, nil, errors.New("邮箱或手机号格式不正确")
 	}
 	if !validator.ValidatePassword(password) {
-		return nil, "", errors.New("密码至少需要6个字符")
+		return nil, nil, errors.New("密码至少需要6个字符")
 	}
 
-	// 验证验证码
-	/*
+	// 验证验证码（REQUIRE_REGISTER_CODE 开启时，生产环境默认开启）
+	if s.cfg.RequireRegisterCode {
 		if err := s.verificationCodeService.ValidateCode(identifier, code, "register"); err != nil {
-			return nil, "", err
-		}*/
+			return nil, nil, err
+		}
+	}
 
 	// 检查用户名是否已存在
 	var existingUser models.User
 	if err := s.db.Where("username = ?", username).First(&existingUser).Error; err == nil {
-		return nil, "", errors.New("用户名已存在")
+		return nil, nil, errors.New("用户名已存在")
 	}
 
 	// 检查邮箱或手机号是否已注册
 	var existingIdentifier models.User
 	if validator.IsEmail(identifier) {
 		if err := s.db.Where("email = ?", identifier).First(&existingIdentifier).Error; err == nil {
-			return nil, "", errors.New("该邮箱已被注册")
+			return nil, nil, errors.New("该邮箱已被注册")
 		}
 	} else {
 		if err := s.db.Where("phone = ?", identifier).First(&existingIdentifier).Error; err == nil {
-			return nil, "", errors.New("该手机号已被注册")
+			return nil, nil, errors.New("该手机号已被注册")
 		}
 	}
 
 	// 加密密码
 	passwordHash, err := s.HashPassword(password)
 	if err != nil {
-		return nil, "", errors.New("密码加密失败")
+		return nil, nil, errors.New("密码加密失败")
 	}
 
 	// 创建用户
@@ -93,52 +111,398 @@ func (s *AuthService) Register(username, identifier, password, code string) (*mo
 	}
 
 	// 生成头像URL
-	avatarURL := fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s", username)
-	user.AvatarURL = &avatarURL
+	user.AvatarURL = defaultAvatarURL(username)
 
 	if err := s.db.Create(&user).Error; err != nil {
-		return nil, "", errors.New("创建用户失败")
+		return nil, nil, errors.New("创建用户失败")
 	}
 
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &user.ID,
+		Action:     models.AuditRegister,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID.String(),
+	})
+
 	// 生成Token
-	token, err := auth.GenerateToken(user.ID, s.cfg.JWTSecret, s.cfg.JWTExpiration)
+	tokens, err := s.tokenService.IssueTokens(user.ID, device)
 	if err != nil {
-		return nil, "", errors.New("生成Token失败")
+		return nil, nil, errors.New("生成Token失败")
 	}
 
-	return &user, token, nil
+	return &user, tokens, nil
 }
 
-func (s *AuthService) Login(identifier, password string) (*models.User, string, error) {
+// Login 密码登录。同一账号或 IP 连续失败后进入指数退避，失败次数过多时锁定账号，
+// 被限制时返回 *LoginThrottledError。开启两步验证的账号不直接登录，而是返回挑战
+func (s *AuthService) Login(identifier, password string, device DeviceInfo) (*models.User, *TokenPair, *TwoFactorChallenge, error) {
+	if !validator.IsEmailOrPhone(identifier) {
+		return nil, nil, nil, errors.New("邮箱或手机号格式不正确")
+	}
+	invalidCredentials := errors.New("邮箱或密码错误")
+	if validator.IsPhone(identifier) {
+		invalidCredentials = errors.New("手机号或密码错误")
+	}
+
+	if err := s.loginThrottle.Check(identifier, device.IP); err != nil {
+		s.auditLoginFailed(nil, identifier, loginMethodPassword, "throttled", device)
+		return nil, nil, nil, err
+	}
+
 	// 查找用户
+	user, err := findUserByIdentifier(s.db, identifier)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			s.loginThrottle.RecordFailure(identifier, device.IP, nil)
+			s.auditLoginFailed(nil, identifier, loginMethodPassword, "unknown_identifier", device)
+			return nil, nil, nil, invalidCredentials
+		}
+		return nil, nil, nil, errors.New("登录失败")
+	}
+
+	if err := s.loginThrottle.CheckUserLocked(user); err != nil {
+		s.auditLoginFailed(user, identifier, loginMethodPassword, "locked", device)
+		return nil, nil, nil, err
+	}
+
+	// 验证密码
+	if !s.VerifyPassword(user.PasswordHash, password) {
+		s.loginThrottle.RecordFailure(identifier, device.IP, user)
+		s.auditLoginFailed(user, identifier, loginMethodPassword, "invalid_password", device)
+		if err := s.loginThrottle.CheckUserLocked(user); err != nil {
+			return nil, nil, nil, err
+		}
+		return nil, nil, nil, invalidCredentials
+	}
+	s.loginThrottle.Reset(identifier)
+
+	tokens, challenge, err := s.completeLogin(user, loginMethodPassword, device)
+	if err != nil {
+		if errors.Is(err, ErrUserSuspended) {
+			s.auditLoginFailed(user, identifier, loginMethodPassword, "suspended", device)
+		}
+		return nil, nil, nil, err
+	}
+	return user, tokens, challenge, nil
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌对（刷新令牌同时轮换）
+func (s *AuthService) RefreshToken(refreshToken string, device DeviceInfo) (*TokenPair, error) {
+	tokens, err := s.tokenService.Refresh(refreshToken)
+	var reused *RefreshTokenReusedError
+	if errors.As(err, &reused) {
+		s.audit.Record(device, models.AuditEvent{
+			ActorID:    &reused.UserID,
+			Action:     models.AuditTokenReuse,
+			TargetType: models.AuditTargetSession,
+			TargetID:   reused.SessionID.String(),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &tokens.UserID,
+		Action:     models.AuditTokenRefresh,
+		TargetType: models.AuditTargetSession,
+		TargetID:   tokens.SessionID.String(),
+	})
+	return tokens, nil
+}
+
+// Logout 退出当前登录会话，返回被结束的会话
+func (s *AuthService) Logout(refreshToken string, device DeviceInfo) (*models.UserSession, error) {
+	session, err := s.tokenService.RevokeByRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &session.UserID,
+		Action:     models.AuditLogout,
+		TargetType: models.AuditTargetSession,
+		TargetID:   session.ID.String(),
+	})
+	return session, nil
+}
+
+// LogoutAll 退出用户在所有设备上的登录
+func (s *AuthService) LogoutAll(userID uuid.UUID, device DeviceInfo) error {
+	if err := s.tokenService.RevokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &userID,
+		Action:     models.AuditLogoutAll,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID.String(),
+	})
+	return nil
+}
+
+// LoginByCode 使用登录验证码登录；账号不存在时按配置自动注册，开启两步验证的账号返回挑战
+func (s *AuthService) LoginByCode(identifier, code string, device DeviceInfo) (*models.User, *TokenPair, *TwoFactorChallenge, bool, error) {
+	if !validator.IsEmailOrPhone(identifier) {
+		return nil, nil, nil, false, errors.New("邮箱或手机号格式不正确")
+	}
+
+	if err := s.verificationCodeService.ValidateCode(identifier, code, "login"); err != nil {
+		s.auditLoginFailed(nil, identifier, loginMethodCode, "invalid_code", device)
+		return nil, nil, nil, false, err
+	}
+
+	created := false
+	user, err := findUserByIdentifier(s.db, identifier)
+	if err == gorm.ErrRecordNotFound {
+		if !s.cfg.CodeLoginAutoRegister {
+			return nil, nil, nil, false, errors.New("账号不存在，请先注册")
+		}
+		user, err = s.createUserWithIdentifier(identifier)
+		if err != nil {
+			return nil, nil, nil, false, errors.New("创建用户失败")
+		}
+		created = true
+	} else if err != nil {
+		return nil, nil, nil, false, errors.New("登录失败")
+	}
+
+	tokens, challenge, err := s.completeLogin(user, loginMethodCode, device)
+	if err != nil {
+		if errors.Is(err, ErrUserSuspended) {
+			s.auditLoginFailed(user, identifier, loginMethodCode, "suspended", device)
+		}
+		return nil, nil, nil, false, err
+	}
+	return user, tokens, challenge, created, nil
+}
+
+// completeLogin 第一步认证通过后：开启了两步验证则签发挑战，否则更新登录时间并签发令牌
+func (s *AuthService) completeLogin(user *models.User, method string, device DeviceInfo) (*TokenPair, *TwoFactorChallenge, error) {
+	enabled, err := s.twoFactor.IsEnabled(user.ID)
+	if err != nil {
+		return nil, nil, errors.New("登录失败")
+	}
+	if enabled {
+		challenge, err := s.twoFactor.IssueChallenge(user.ID)
+		if err != nil {
+			return nil, nil, errors.New("登录失败")
+		}
+		s.audit.Record(device, models.AuditEvent{
+			ActorID:    &user.ID,
+			Action:     models.AuditTwoFactorRequired,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID.String(),
+			Metadata:   models.JSONB{"method": method},
+		})
+		return nil, challenge, nil
+	}
+
+	tokens, err := s.issueLoginTokens(user, method, device)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, nil, nil
+}
+
+// issueLoginTokens 更新最后登录时间并签发令牌
+func (s *AuthService) issueLoginTokens(user *models.User, method string, device DeviceInfo) (*TokenPair, error) {
+	now := time.Now()
+	user.LastLoginAt = &now
+	s.db.Model(user).Update("last_login_at", now)
+
+	tokens, err := s.tokenService.IssueTokens(user.ID, device)
+	if err != nil {
+		if errors.Is(err, ErrUserSuspended) {
+			return nil, err
+		}
+		return nil, errors.New("生成Token失败")
+	}
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &user.ID,
+		Action:     models.AuditLogin,
+		TargetType: models.AuditTargetSession,
+		TargetID:   tokens.SessionID.String(),
+		Metadata:   models.JSONB{"method": method},
+	})
+	return tokens, nil
+}
+
+// VerifyTwoFactor 登录第二步：校验挑战令牌和动态验证码（或恢复码）后签发令牌
+func (s *AuthService) VerifyTwoFactor(challengeToken, code string, device DeviceInfo) (*models.User, *TokenPair, error) {
+	userID, err := s.twoFactor.VerifyChallenge(challengeToken, code, device.IP)
+	if err != nil {
+		if userID != uuid.Nil {
+			s.audit.Record(device, models.AuditEvent{
+				ActorID:    &userID,
+				Action:     models.AuditTwoFactorFailed,
+				TargetType: models.AuditTargetUser,
+				TargetID:   userID.String(),
+				Metadata:   models.JSONB{"error": err.Error()},
+			})
+		}
+		return nil, nil, err
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, nil, ErrTwoFactorChallenge
+	}
+
+	tokens, err := s.issueLoginTokens(&user, loginMethodTwoFactor, device)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, tokens, nil
+}
+
+// auditLoginFailed 记录登录失败；user 为空表示账号不存在或尚未识别
+func (s *AuthService) auditLoginFailed(user *models.User, identifier, method, reason string, device DeviceInfo) {
+	event := models.AuditEvent{
+		Action:   models.AuditLoginFailed,
+		Metadata: models.JSONB{"identifier": identifier, "method": method, "reason": reason},
+	}
+	if user != nil {
+		event.ActorID = &user.ID
+		event.TargetType = models.AuditTargetUser
+		event.TargetID = user.ID.String()
+	}
+	s.audit.Record(device, event)
+}
+
+// createUserWithIdentifier 为已验证的邮箱/手机号创建一个未设置密码的账号
+func (s *AuthService) createUserWithIdentifier(identifier string) (*models.User, error) {
+	username, err := generateUsername(s.db, "用户")
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		Username:  username,
+		AvatarURL: defaultAvatarURL(username),
+	}
+	if validator.IsEmail(identifier) {
+		user.Email = &identifier
+	} else {
+		user.Phone = &identifier
+	}
+
+	if err := s.db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// generateUsername 生成一个未被占用的随机用户名
+func generateUsername(db *gorm.DB, prefix string) (string, error) {
+	for i := 0; i < 5; i++ {
+		username := prefix + strings.ReplaceAll(uuid.New().String(), "-", "")[:8]
+		var count int64
+		if err := db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return username, nil
+		}
+	}
+	return "", errors.New("生成用户名失败")
+}
+
+// defaultAvatarURL 根据用户名生成默认头像
+func defaultAvatarURL(username string) *string {
+	avatarURL := fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s", username)
+	return &avatarURL
+}
+
+// findUserByIdentifier 根据邮箱或手机号查找用户
+func findUserByIdentifier(db *gorm.DB, identifier string) (*models.User, error) {
 	var user models.User
 	if validator.IsEmail(identifier) {
-		if err := s.db.Where("email = ?", identifier).First(&user).Error; err != nil {
-			return nil, "", errors.New("邮箱或密码错误")
+		if err := db.Where("email = ?", identifier).First(&user).Error; err != nil {
+			return nil, err
 		}
 	} else if validator.IsPhone(identifier) {
-		if err := s.db.Where("phone = ?", identifier).First(&user).Error; err != nil {
-			return nil, "", errors.New("手机号或密码错误")
+		if err := db.Where("phone = ?", identifier).First(&user).Error; err != nil {
+			return nil, err
 		}
 	} else {
-		return nil, "", errors.New("邮箱或手机号格式不正确")
+		return nil, errors.New("邮箱或手机号格式不正确")
 	}
+	return &user, nil
+}
 
-	// 验证密码
-	if !s.VerifyPassword(user.PasswordHash, password) {
-		return nil, "", errors.New("邮箱或密码错误")
+// ResetPassword 通过验证码重置密码，并使该用户所有已登录会话失效
+func (s *AuthService) ResetPassword(identifier, code, newPassword string, device DeviceInfo) (*models.User, error) {
+	if !validator.ValidatePassword(newPassword) {
+		return nil, errors.New("密码至少需要6个字符")
 	}
 
-	// 更新最后登录时间
-	now := time.Now()
-	user.LastLoginAt = &now
-	s.db.Save(&user)
+	if err := s.verificationCodeService.ValidateCode(identifier, code, "reset_password"); err != nil {
+		return nil, err
+	}
 
-	// 生成Token
-	token, err := auth.GenerateToken(user.ID, s.cfg.JWTSecret, s.cfg.JWTExpiration)
+	user, err := findUserByIdentifier(s.db, identifier)
 	if err != nil {
-		return nil, "", errors.New("生成Token失败")
+		return nil, errors.New("账号不存在")
+	}
+
+	passwordHash, err := s.HashPassword(newPassword)
+	if err != nil {
+		return nil, errors.New("密码加密失败")
+	}
+	if err := s.db.Model(user).Update("password_hash", passwordHash).Error; err != nil {
+		return nil, errors.New("重置密码失败")
+	}
+
+	if err := s.loginThrottle.Unlock(user); err != nil {
+		utils.APILog("[AuthService.ResetPassword] ⚠️ 用户 %s 重置密码后解除锁定失败: %v", user.ID, err)
+	}
+	if err := s.tokenService.RevokeAllSessions(user.ID); err != nil {
+		utils.APILog("[AuthService.ResetPassword] ⚠️ 用户 %s 重置密码后吊销会话失败: %v", user.ID, err)
+	}
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &user.ID,
+		Action:     models.AuditPasswordReset,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID.String(),
+	})
+
+	return user, nil
+}
+
+// ChangePassword 校验旧密码后修改密码，并使除当前会话外的其他会话失效
+func (s *AuthService) ChangePassword(userID, currentSessionID uuid.UUID, oldPassword, newPassword string, device DeviceInfo) error {
+	if !validator.ValidatePassword(newPassword) {
+		return errors.New("密码至少需要6个字符")
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if !s.VerifyPassword(user.PasswordHash, oldPassword) {
+		return errors.New("原密码错误")
+	}
+
+	passwordHash, err := s.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+	if err := s.db.Model(&user).Update("password_hash", passwordHash).Error; err != nil {
+		return errors.New("修改密码失败")
+	}
+
+	if err := s.tokenService.RevokeOtherSessions(userID, currentSessionID); err != nil {
+		utils.APILog("[AuthService.ChangePassword] ⚠️ 用户 %s 修改密码后吊销其他会话失败: %v", userID, err)
 	}
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &userID,
+		Action:     models.AuditPasswordChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID.String(),
+	})
 
-	return &user, token, nil
+	return nil
 }
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+
+	"fluent-life-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCannotBlockSelf = errors.New("不能拉黑或静音自己")
+	ErrUserBlocked     = errors.New("你与该用户存在拉黑关系")
+	ErrBlockedByHost   = errors.New("房主已将你拉黑，无法加入该房间")
+)
+
+// BlockService 拉黑与静音
+type BlockService struct {
+	db *gorm.DB
+}
+
+func NewBlockService(db *gorm.DB) *BlockService {
+	return &BlockService{db: db}
+}
+
+// Block 拉黑或静音用户。拉黑会同时解除双方的关注关系；已拉黑的用户再静音不会降级
+func (s *BlockService) Block(blockerID, blockedID uuid.UUID, kind string) (*models.UserBlock, error) {
+	if blockerID == blockedID {
+		return nil, ErrCannotBlockSelf
+	}
+	var target models.User
+	if err := s.db.Select("id").First(&target, "id = ?", blockedID).Error; err != nil {
+		return nil, err
+	}
+
+	block := models.UserBlock{BlockerID: blockerID, BlockedID: blockedID, Kind: kind}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.UserBlock
+		err := tx.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).First(&existing).Error
+		if err == nil && existing.Kind == models.BlockKindBlock {
+			block = existing
+			return nil
+		}
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "blocker_id"}, {Name: "blocked_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"kind"}),
+		}).Create(&block).Error; err != nil {
+			return err
+		}
+
+		if kind != models.BlockKindBlock {
+			return nil
+		}
+		follows := NewFollowService(tx)
+		if err := follows.UnfollowUser(blockerID, blockedID); err != nil {
+			return err
+		}
+		return follows.UnfollowUser(blockedID, blockerID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Unblock 解除拉黑或静音
+func (s *BlockService) Unblock(blockerID, blockedID uuid.UUID, kind string) error {
+	return s.db.Where("blocker_id = ? AND blocked_id = ? AND kind = ?", blockerID, blockedID, kind).
+		Delete(&models.UserBlock{}).Error
+}
+
+// List 获取自己拉黑或静音的用户
+func (s *BlockService) List(blockerID uuid.UUID, kind string, page, pageSize int) ([]models.UserBlock, int64, error) {
+	var blocks []models.UserBlock
+	var total int64
+
+	query := s.db.Model(&models.UserBlock{}).Where("blocker_id = ? AND kind = ?", blockerID, kind)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Preload("Blocked", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "avatar_url")
+	}).Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&blocks).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return blocks, total, nil
+}
+
+// IsBlocked 判断 blockerID 是否拉黑了 blockedID
+func (s *BlockService) IsBlocked(blockerID, blockedID uuid.UUID) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.UserBlock{}).
+		Where("blocker_id = ? AND blocked_id = ? AND kind = ?", blockerID, blockedID, models.BlockKindBlock).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// isBlockedEither 判断两个用户之间是否有任意一方拉黑了另一方
+func isBlockedEither(db *gorm.DB, a, b uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.UserBlock{}).
+		Where("kind = ? AND ((blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?))", models.BlockKindBlock, a, b, b, a).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// excludeHiddenAuthors 过滤掉 viewerID 拉黑/静音的用户以及拉黑了 viewerID 的用户发布的内容，
+// column 为内容表中作者的列名
+func excludeHiddenAuthors(viewerID uuid.UUID, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		blockedByViewer := db.Session(&gorm.Session{NewDB: true}).Model(&models.UserBlock{}).
+			Select("blocked_id").Where("blocker_id = ?", viewerID)
+		blockingViewer := db.Session(&gorm.Session{NewDB: true}).Model(&models.UserBlock{}).
+			Select("blocker_id").Where("blocked_id = ? AND kind = ?", viewerID, models.BlockKindBlock)
+		return db.Where(column+" NOT IN (?)", blockedByViewer).Where(column+" NOT IN (?)", blockingViewer)
+	}
+}
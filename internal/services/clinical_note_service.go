@@ -0,0 +1,285 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"fluent-life-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrNoteVersionConflict = errors.New("记录已被修改，请刷新后重试")
+
+const (
+	maxNoteTags        = 20
+	maxNoteTagLength   = 30
+	maxNoteRecordLinks = 50
+)
+
+// ClinicalNoteService 治疗师的临床记录：只有撰写的治疗师可以查看和修改，
+// 分享后客户可以查看；每次修改都保存版本快照
+type ClinicalNoteService struct {
+	db *gorm.DB
+}
+
+func NewClinicalNoteService(db *gorm.DB) *ClinicalNoteService {
+	return &ClinicalNoteService{db: db}
+}
+
+// ClinicalNoteInput 创建记录时填写的内容
+type ClinicalNoteInput struct {
+	SessionDate       time.Time
+	Content           string
+	Tags              []string
+	TrainingRecordIDs []uuid.UUID
+	SpeechAnalysis    string
+	SharedWithClient  bool
+}
+
+// ClinicalNoteUpdate 修改记录，只更新非 nil 字段。Version 不为 0 时必须等于当前版本，用于防止覆盖他人的修改
+type ClinicalNoteUpdate struct {
+	Version           int
+	SessionDate       *time.Time
+	Content           *string
+	Tags              *[]string
+	TrainingRecordIDs *[]uuid.UUID
+	SpeechAnalysis    *string
+	SharedWithClient  *bool
+}
+
+// normalizeNoteTags 去掉空白和重复标签
+func normalizeNoteTags(tags []string) (models.StringList, error) {
+	unique := make(models.StringList, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || unique.Contains(tag) {
+			continue
+		}
+		if len([]rune(tag)) > maxNoteTagLength {
+			return nil, errors.New("标签不能超过30个字符")
+		}
+		unique = append(unique, tag)
+	}
+	if len(unique) > maxNoteTags {
+		return nil, errors.New("标签不能超过20个")
+	}
+	return unique, nil
+}
+
+// checkRecordLinks 关联的训练记录必须属于该客户，且客户授权了 training_records
+func (s *ClinicalNoteService) checkRecordLinks(link *models.TherapistLink, recordIDs []uuid.UUID) (models.StringList, error) {
+	ids := make(models.StringList, 0, len(recordIDs))
+	for _, id := range recordIDs {
+		if !ids.Contains(id.String()) {
+			ids = append(ids, id.String())
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	if len(ids) > maxNoteRecordLinks {
+		return nil, errors.New("关联的训练记录不能超过50条")
+	}
+	if !link.Allows(models.ConsentTrainingRecords) {
+		return nil, ErrConsentNotGranted
+	}
+
+	var count int64
+	if err := s.db.Model(&models.TrainingRecord{}).
+		Where("id IN ? AND user_id = ?", []string(ids), *link.ClientID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count != int64(len(ids)) {
+		return nil, errors.New("关联的训练记录不存在或不属于该客户")
+	}
+	return ids, nil
+}
+
+// Create 治疗师为已关联的客户撰写记录
+func (s *ClinicalNoteService) Create(therapistID, clientID uuid.UUID, input ClinicalNoteInput) (*models.ClinicalNote, error) {
+	link, err := findActiveLink(s.db, therapistID, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(input.Content)
+	if content == "" {
+		return nil, errors.New("记录内容不能为空")
+	}
+	tags, err := normalizeNoteTags(input.Tags)
+	if err != nil {
+		return nil, err
+	}
+	recordIDs, err := s.checkRecordLinks(link, input.TrainingRecordIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	note := models.ClinicalNote{
+		TherapistID:       therapistID,
+		ClientID:          clientID,
+		SessionDate:       input.SessionDate,
+		Content:           content,
+		Tags:              tags,
+		TrainingRecordIDs: recordIDs,
+		SpeechAnalysis:    strings.TrimSpace(input.SpeechAnalysis),
+		SharedWithClient:  input.SharedWithClient,
+		Version:           1,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		version := models.NewClinicalNoteVersion(&note, models.NoteActionCreate, therapistID)
+		return tx.Create(&version).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// ListForTherapist 治疗师为某个客户撰写的记录，tag 不为空时按标签过滤
+func (s *ClinicalNoteService) ListForTherapist(therapistID, clientID uuid.UUID, tag string, page, pageSize int) ([]models.ClinicalNote, int64, error) {
+	query := s.db.Model(&models.ClinicalNote{}).
+		Where("therapist_id = ? AND client_id = ? AND deleted_at IS NULL", therapistID, clientID)
+	if tag != "" {
+		tagJSON, _ := json.Marshal([]string{tag})
+		query = query.Where("tags @> ?", string(tagJSON))
+	}
+	return s.list(query, "Client", page, pageSize)
+}
+
+// ListForClient 治疗师分享给客户的记录
+func (s *ClinicalNoteService) ListForClient(clientID uuid.UUID, page, pageSize int) ([]models.ClinicalNote, int64, error) {
+	query := s.db.Model(&models.ClinicalNote{}).
+		Where("client_id = ? AND shared_with_client = ? AND deleted_at IS NULL", clientID, true)
+	return s.list(query, "Therapist", page, pageSize)
+}
+
+func (s *ClinicalNoteService) list(query *gorm.DB, preload string, page, pageSize int) ([]models.ClinicalNote, int64, error) {
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var notes []models.ClinicalNote
+	err := query.Preload(preload, preloadBasicUser).
+		Order("session_date DESC, created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&notes).Error
+	return notes, total, err
+}
+
+// Get 治疗师查看自己撰写的一条记录
+func (s *ClinicalNoteService) Get(therapistID, noteID uuid.UUID) (*models.ClinicalNote, error) {
+	var note models.ClinicalNote
+	if err := s.db.Preload("Client", preloadBasicUser).
+		Where("id = ? AND therapist_id = ? AND deleted_at IS NULL", noteID, therapistID).
+		First(&note).Error; err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// Update 修改记录并保存新版本，修改关联的训练记录时需要客户仍然授权
+func (s *ClinicalNoteService) Update(therapistID, noteID uuid.UUID, update ClinicalNoteUpdate) (*models.ClinicalNote, error) {
+	note, err := s.Get(therapistID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if update.Version != 0 && update.Version != note.Version {
+		return nil, ErrNoteVersionConflict
+	}
+
+	if update.SessionDate != nil {
+		note.SessionDate = *update.SessionDate
+	}
+	if update.Content != nil {
+		content := strings.TrimSpace(*update.Content)
+		if content == "" {
+			return nil, errors.New("记录内容不能为空")
+		}
+		note.Content = content
+	}
+	if update.Tags != nil {
+		if note.Tags, err = normalizeNoteTags(*update.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if update.TrainingRecordIDs != nil {
+		link, err := findActiveLink(s.db, therapistID, note.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if note.TrainingRecordIDs, err = s.checkRecordLinks(link, *update.TrainingRecordIDs); err != nil {
+			return nil, err
+		}
+	}
+	if update.SpeechAnalysis != nil {
+		note.SpeechAnalysis = strings.TrimSpace(*update.SpeechAnalysis)
+	}
+	if update.SharedWithClient != nil {
+		note.SharedWithClient = *update.SharedWithClient
+	}
+
+	if err := s.saveVersion(note, models.NoteActionUpdate, therapistID, map[string]interface{}{
+		"session_date":        note.SessionDate,
+		"content":             note.Content,
+		"tags":                note.Tags,
+		"training_record_ids": note.TrainingRecordIDs,
+		"speech_analysis":     note.SpeechAnalysis,
+		"shared_with_client":  note.SharedWithClient,
+	}); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Delete 标记删除记录，历史版本保留
+func (s *ClinicalNoteService) Delete(therapistID, noteID uuid.UUID) error {
+	note, err := s.Get(therapistID, noteID)
+	if err != nil {
+		return err
+	}
+	return s.saveVersion(note, models.NoteActionDelete, therapistID, map[string]interface{}{"deleted_at": time.Now()})
+}
+
+// saveVersion 以当前版本号为条件更新记录并追加版本快照，版本号不一致时返回 ErrNoteVersionConflict
+func (s *ClinicalNoteService) saveVersion(note *models.ClinicalNote, action string, editorID uuid.UUID, updates map[string]interface{}) error {
+	current := note.Version
+	note.Version = current + 1
+	updates["version"] = note.Version
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ClinicalNote{}).
+			Where("id = ? AND version = ? AND deleted_at IS NULL", note.ID, current).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNoteVersionConflict
+		}
+		version := models.NewClinicalNoteVersion(note, action, editorID)
+		return tx.Create(&version).Error
+	})
+}
+
+// Versions 记录的全部历史版本（含已删除的记录），按版本号升序
+func (s *ClinicalNoteService) Versions(therapistID, noteID uuid.UUID) ([]models.ClinicalNoteVersion, error) {
+	var count int64
+	if err := s.db.Model(&models.ClinicalNote{}).Where("id = ? AND therapist_id = ?", noteID, therapistID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var versions []models.ClinicalNoteVersion
+	err := s.db.Where("note_id = ?", noteID).Order("version ASC").Find(&versions).Error
+	return versions, err
+}
@@ -10,6 +10,7 @@ type CommunityService struct {
 	db *gorm.DB
 	followService *FollowService
 	collectionService *CollectionService // Add CollectionService
+	privacyService *PrivacyService
 }
 
 func NewCommunityService(db *gorm.DB) *CommunityService {
@@ -17,6 +18,7 @@ func NewCommunityService(db *gorm.DB) *CommunityService {
 		db: db,
 		followService: NewFollowService(db),
 		collectionService: NewCollectionService(db), // Initialize CollectionService
+		privacyService: NewPrivacyService(db),
 	}
 }
 
@@ -37,11 +39,15 @@ func (s *CommunityService) CreatePost(userID uuid.UUID, content, tag, imageURL s
 	return &post, nil
 }
 
+// GetPosts 获取帖子列表，登录用户看不到与自己存在拉黑关系或被自己静音的用户的帖子
 func (s *CommunityService) GetPosts(page, pageSize int, sortBy, tag string, userID *uuid.UUID) ([]models.Post, int64, error) {
 	var posts []models.Post
 	var total int64
 
 	query := s.db.Model(&models.Post{})
+	if userID != nil {
+		query = query.Scopes(excludeHiddenAuthors(*userID, "user_id"))
+	}
 
 	// Filtering by tag
 	if tag != "" {
@@ -97,9 +103,16 @@ func (s *CommunityService) GetPosts(page, pageSize int, sortBy, tag string, user
 	return posts, total, nil
 }
 
-func (s *CommunityService) GetPost(postID uuid.UUID) (*models.Post, error) {
+// GetPost 获取帖子详情。viewerID 不为空时，作者与其存在拉黑关系或被其静音的帖子视为不存在，
+// 评论中同样过滤掉这些用户
+func (s *CommunityService) GetPost(postID uuid.UUID, viewerID *uuid.UUID) (*models.Post, error) {
 	var post models.Post
-	if err := s.db.Preload("User").Preload("Likes").Preload("Comments.User").First(&post, postID).Error; err != nil {
+	query := s.db.Preload("User").Preload("Likes")
+	if viewerID != nil {
+		query = query.Scopes(excludeHiddenAuthors(*viewerID, "user_id")).
+			Preload("Comments", excludeHiddenAuthors(*viewerID, "user_id"))
+	}
+	if err := query.Preload("Comments.User").First(&post, postID).Error; err != nil {
 		return nil, err
 	}
 	return &post, nil
@@ -128,7 +141,20 @@ func (s *CommunityService) ToggleLike(userID, postID uuid.UUID) (bool, error) {
 	return false, err
 }
 
+// CreateComment 发表评论，需符合帖子作者设置的评论权限
 func (s *CommunityService) CreateComment(userID, postID uuid.UUID, content string) (*models.Comment, error) {
+	var post models.Post
+	if err := s.db.Select("id", "user_id").First(&post, postID).Error; err != nil {
+		return nil, err
+	}
+	allowed, err := s.privacyService.CanComment(userID, post.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrCommentNotAllowed
+	}
+
 	comment := models.Comment{
 		UserID:  userID,
 		PostID:  postID,
@@ -144,9 +170,14 @@ func (s *CommunityService) CreateComment(userID, postID uuid.UUID, content strin
 	return &comment, nil
 }
 
-func (s *CommunityService) GetComments(postID uuid.UUID) ([]models.Comment, error) {
+// GetComments 获取帖子的评论，viewerID 不为空时过滤掉与其存在拉黑关系或被其静音的用户的评论
+func (s *CommunityService) GetComments(postID uuid.UUID, viewerID *uuid.UUID) ([]models.Comment, error) {
 	var comments []models.Comment
-	if err := s.db.Preload("User").Where("post_id = ?", postID).Order("created_at ASC").Find(&comments).Error; err != nil {
+	query := s.db.Preload("User").Where("post_id = ?", postID)
+	if viewerID != nil {
+		query = query.Scopes(excludeHiddenAuthors(*viewerID, "user_id"))
+	}
+	if err := query.Order("created_at ASC").Find(&comments).Error; err != nil {
 		return nil, err
 	}
 	return comments, nil
@@ -162,6 +193,25 @@ func (s *CommunityService) DeletePost(userID, postID uuid.UUID) error {
 		return gorm.ErrRecordNotFound // Or a custom error for unauthorized
 	}
 
+	return s.removePost(post)
+}
+
+// ModerateDeletePost 管理员/版主删除任意帖子
+func (s *CommunityService) ModerateDeletePost(postID uuid.UUID) (*models.Post, error) {
+	var post models.Post
+	if err := s.db.First(&post, postID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.removePost(post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// removePost 删除帖子及其评论、点赞和收藏
+func (s *CommunityService) removePost(post models.Post) error {
+	postID := post.ID
+
 	// Delete associated comments and their likes
 	var comments []models.Comment
 	if err := s.db.Where("post_id = ?", postID).Find(&comments).Error; err != nil {
@@ -204,6 +254,25 @@ func (s *CommunityService) DeleteComment(userID, commentID uuid.UUID) error {
 		return gorm.ErrRecordNotFound // Or a custom error for unauthorized
 	}
 
+	return s.removeComment(comment)
+}
+
+// ModerateDeleteComment 管理员/版主删除任意评论
+func (s *CommunityService) ModerateDeleteComment(commentID uuid.UUID) (*models.Comment, error) {
+	var comment models.Comment
+	if err := s.db.First(&comment, commentID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.removeComment(comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// removeComment 删除评论及其点赞，并更新帖子评论数
+func (s *CommunityService) removeComment(comment models.Comment) error {
+	commentID := comment.ID
+
 	// Delete associated comment likes
 	if err := s.db.Where("comment_id = ?", commentID).Delete(&models.CommentLike{}).Error; err != nil {
 		return err
@@ -243,10 +312,23 @@ func (s *CommunityService) ToggleCommentLike(userID, commentID uuid.UUID) (bool,
 	return false, err
 }
 
+// GetUserPosts 获取某个用户的帖子列表，对方主页对当前用户不可见时返回 ErrProfileHidden
 func (s *CommunityService) GetUserPosts(targetUserID uuid.UUID, page, pageSize int, currentUserID *uuid.UUID) ([]models.Post, int64, error) {
 	var posts []models.Post
 	var total int64
 
+	viewerID := uuid.Nil
+	if currentUserID != nil {
+		viewerID = *currentUserID
+	}
+	visible, _, err := s.privacyService.ProfileAccess(viewerID, targetUserID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !visible {
+		return nil, 0, ErrProfileHidden
+	}
+
 	query := s.db.Model(&models.Post{}).Where("user_id = ?", targetUserID)
 	query.Count(&total)
 
@@ -14,13 +14,21 @@ func NewFollowService(db *gorm.DB) *FollowService {
 	return &FollowService{db: db}
 }
 
-// FollowUser 关注用户
+// FollowUser 关注用户，双方存在拉黑关系时返回 ErrUserBlocked
 func (s *FollowService) FollowUser(followerID, followeeID uuid.UUID) error {
 	// 不能关注自己
 	if followerID == followeeID {
 		return gorm.ErrInvalidTransaction
 	}
 
+	blocked, err := isBlockedEither(s.db, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrUserBlocked
+	}
+
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// 检查是否已经关注
 		var existingFollow models.Follow
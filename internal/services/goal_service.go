@@ -0,0 +1,416 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTooManyGoals = errors.New("最多只能设置20个目标")
+
+const (
+	maxGoalsPerUser = 20
+	// maxGoalPeriodsPerRun 每个目标每次最多结算的周期数，避免长时间停机后单次任务过久
+	maxGoalPeriodsPerRun = 60
+)
+
+// goalMilestones 累计达成目标的次数里程碑，达到后解锁对应成就
+var goalMilestones = map[int64]string{
+	1:  "goal_first",
+	30: "goal_30",
+}
+
+// GoalService 用户的每日/每周练习目标：进度按当前周期（用户当地日历）的训练记录实时计算，
+// 达成时写入历史并发送通知，周期结束后由后台任务补全未达成的周期
+type GoalService struct {
+	db                  *gorm.DB
+	notificationService *NotificationService
+	achievementService  *AchievementService
+}
+
+func NewGoalService(db *gorm.DB) *GoalService {
+	return &GoalService{
+		db:                  db,
+		notificationService: NewNotificationService(db),
+		achievementService:  NewAchievementService(db),
+	}
+}
+
+// GoalInput 创建目标时填写的内容
+type GoalInput struct {
+	Period string
+	Metric string
+	Type   string // 为空表示全部训练类型
+	Target int
+}
+
+// GoalUpdate 修改目标，只更新非 nil 字段。周期和指标不能修改，以免历史记录失去可比性
+type GoalUpdate struct {
+	Target *int
+	Active *bool
+}
+
+// GoalProgress 目标及其当前周期的进度
+type GoalProgress struct {
+	models.Goal
+	Description string    `json:"description"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Progress    int       `json:"progress"`
+	Hit         bool      `json:"hit"`
+}
+
+// goalPeriodStart t 所在周期在 loc 时区的开始时间，每周从周一开始
+func goalPeriodStart(period string, t time.Time, loc *time.Location) time.Time {
+	start := utils.StartOfDay(t, loc)
+	if period == models.GoalWeekly {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	}
+	return start
+}
+
+// goalPeriodEnd 从 start 开始的周期在 loc 时区的结束时间
+func goalPeriodEnd(period string, start time.Time, loc *time.Location) time.Time {
+	if period == models.GoalWeekly {
+		return start.In(loc).AddDate(0, 0, 7)
+	}
+	return start.In(loc).AddDate(0, 0, 1)
+}
+
+// goalDescription 目标的文字描述，如"每天冥想 15 分钟"、"每周脱敏训练 3 次"
+func goalDescription(goal *models.Goal) string {
+	period := "每天"
+	if goal.Period == models.GoalWeekly {
+		period = "每周"
+	}
+	typeName := "训练"
+	if name, ok := trainingTypeNames[goal.Type]; ok {
+		typeName = name
+	}
+	unit := "次"
+	if goal.Metric == models.GoalMetricMinutes {
+		unit = "分钟"
+	}
+	return fmt.Sprintf("%s%s %d %s", period, typeName, goal.Target, unit)
+}
+
+func validateGoalTarget(target int) error {
+	if target <= 0 || target > 10000 {
+		return errors.New("目标值必须在 1 到 10000 之间")
+	}
+	return nil
+}
+
+// Create 创建目标，从当前周期开始计算
+func (s *GoalService) Create(userID uuid.UUID, input GoalInput) (*GoalProgress, error) {
+	if input.Period != models.GoalDaily && input.Period != models.GoalWeekly {
+		return nil, errors.New("无效的目标周期")
+	}
+	if input.Metric != models.GoalMetricMinutes && input.Metric != models.GoalMetricSessions {
+		return nil, errors.New("无效的目标指标")
+	}
+	if _, ok := trainingTypeNames[input.Type]; input.Type != "" && !ok {
+		return nil, errors.New("无效的训练类型")
+	}
+	if err := validateGoalTarget(input.Target); err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Goal{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count >= maxGoalsPerUser {
+		return nil, ErrTooManyGoals
+	}
+
+	loc := userLocation(s.db, userID)
+	goal := models.Goal{
+		UserID:          userID,
+		Period:          input.Period,
+		Metric:          input.Metric,
+		Type:            input.Type,
+		Target:          input.Target,
+		Active:          true,
+		NextPeriodStart: goalPeriodStart(input.Period, time.Now(), loc),
+	}
+	if err := s.db.Create(&goal).Error; err != nil {
+		return nil, err
+	}
+	return s.withProgress(&goal, loc)
+}
+
+// List 用户的全部目标及当前周期进度
+func (s *GoalService) List(userID uuid.UUID) ([]GoalProgress, error) {
+	var goals []models.Goal
+	if err := s.db.Where("user_id = ?", userID).Order("active DESC, created_at ASC").Find(&goals).Error; err != nil {
+		return nil, err
+	}
+
+	loc := userLocation(s.db, userID)
+	result := make([]GoalProgress, 0, len(goals))
+	for i := range goals {
+		progress, err := s.withProgress(&goals[i], loc)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *progress)
+	}
+	return result, nil
+}
+
+// Get 单个目标及当前周期进度
+func (s *GoalService) Get(userID, goalID uuid.UUID) (*GoalProgress, error) {
+	goal, err := s.find(userID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withProgress(goal, userLocation(s.db, userID))
+}
+
+func (s *GoalService) find(userID, goalID uuid.UUID) (*models.Goal, error) {
+	var goal models.Goal
+	if err := s.db.Where("id = ? AND user_id = ?", goalID, userID).First(&goal).Error; err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+// Update 修改目标值或暂停/恢复目标。恢复时从当前周期重新开始，暂停期间的周期不计入历史
+func (s *GoalService) Update(userID, goalID uuid.UUID, update GoalUpdate) (*GoalProgress, error) {
+	goal, err := s.find(userID, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := userLocation(s.db, userID)
+	updates := map[string]interface{}{}
+	if update.Target != nil {
+		if err := validateGoalTarget(*update.Target); err != nil {
+			return nil, err
+		}
+		goal.Target = *update.Target
+		updates["target"] = goal.Target
+	}
+	if update.Active != nil && *update.Active != goal.Active {
+		goal.Active = *update.Active
+		updates["active"] = goal.Active
+		if goal.Active {
+			goal.NextPeriodStart = goalPeriodStart(goal.Period, time.Now(), loc)
+			updates["next_period_start"] = goal.NextPeriodStart
+		}
+	}
+	if len(updates) > 0 {
+		if err := s.db.Model(goal).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return s.withProgress(goal, loc)
+}
+
+// Delete 删除目标及其历史
+func (s *GoalService) Delete(userID, goalID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", goalID, userID).Delete(&models.Goal{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("goal_id = ?", goalID).Delete(&models.GoalPeriod{}).Error
+	})
+}
+
+// History 目标已结算和已达成的周期，按时间倒序
+func (s *GoalService) History(userID, goalID uuid.UUID, page, pageSize int) ([]models.GoalPeriod, int64, error) {
+	if _, err := s.find(userID, goalID); err != nil {
+		return nil, 0, err
+	}
+
+	query := s.db.Model(&models.GoalPeriod{}).Where("goal_id = ?", goalID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var periods []models.GoalPeriod
+	err := query.Order("period_start DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&periods).Error
+	return periods, total, err
+}
+
+// achieved 目标在 [start, end) 内的完成量
+func (s *GoalService) achieved(goal *models.Goal, start, end time.Time) (int, error) {
+	query := s.db.Model(&models.TrainingRecord{}).
+		Where("user_id = ? AND timestamp >= ? AND timestamp < ?", goal.UserID, start, end)
+	if goal.Type != "" {
+		query = query.Where("type = ?", goal.Type)
+	}
+
+	var value int
+	if goal.Metric == models.GoalMetricMinutes {
+		err := query.Select("COALESCE(SUM(duration), 0) / 60").Scan(&value).Error
+		return value, err
+	}
+	err := query.Select("COUNT(*)").Scan(&value).Error
+	return value, err
+}
+
+func (s *GoalService) withProgress(goal *models.Goal, loc *time.Location) (*GoalProgress, error) {
+	start := goalPeriodStart(goal.Period, time.Now(), loc)
+	end := goalPeriodEnd(goal.Period, start, loc)
+	progress, err := s.achieved(goal, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return &GoalProgress{
+		Goal:        *goal,
+		Description: goalDescription(goal),
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Progress:    progress,
+		Hit:         progress >= goal.Target,
+	}, nil
+}
+
+// RecordProgress 用户记录训练后调用：相关目标在记录所在周期首次达成时写入历史、发送通知并检查成就。
+// 已结算的周期不再变化。失败只记录日志，不影响训练记录的创建
+func (s *GoalService) RecordProgress(record *models.TrainingRecord) {
+	var goals []models.Goal
+	if err := s.db.Where("user_id = ? AND active = ? AND (type = '' OR type = ?)", record.UserID, true, record.Type).
+		Find(&goals).Error; err != nil {
+		utils.APILog("[GoalService.RecordProgress] ❌ 查询用户 %s 的目标失败: %v", record.UserID, err)
+		return
+	}
+	if len(goals) == 0 {
+		return
+	}
+
+	loc := userLocation(s.db, record.UserID)
+	hits := 0
+	for i := range goals {
+		goal := &goals[i]
+		start := goalPeriodStart(goal.Period, record.Timestamp, loc)
+		if start.Before(goal.NextPeriodStart) {
+			continue
+		}
+		end := goalPeriodEnd(goal.Period, start, loc)
+		achieved, err := s.achieved(goal, start, end)
+		if err != nil {
+			utils.APILog("[GoalService.RecordProgress] ❌ 计算目标 %s 的进度失败: %v", goal.ID, err)
+			continue
+		}
+		if achieved < goal.Target {
+			continue
+		}
+
+		now := time.Now()
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.GoalPeriod{
+			GoalID:      goal.ID,
+			UserID:      goal.UserID,
+			PeriodStart: start,
+			PeriodEnd:   end,
+			Target:      goal.Target,
+			Achieved:    achieved,
+			Hit:         true,
+			HitAt:       &now,
+		})
+		if result.Error != nil {
+			utils.APILog("[GoalService.RecordProgress] ❌ 记录目标 %s 达成失败: %v", goal.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue // 本周期已达成过
+		}
+		hits++
+		s.notificationService.Notify(goal.UserID, models.NotificationGoalCompleted, "目标已达成",
+			fmt.Sprintf("恭喜你完成了目标：%s", goalDescription(goal)), models.JSONB{
+				"goal_id":      goal.ID.String(),
+				"period_start": start.Format("2006-01-02"),
+				"achieved":     achieved,
+				"target":       goal.Target,
+			})
+	}
+	if hits > 0 {
+		s.checkMilestones(record.UserID)
+	}
+}
+
+// checkMilestones 按累计达成次数解锁成就
+func (s *GoalService) checkMilestones(userID uuid.UUID) {
+	var hitCount int64
+	if err := s.db.Model(&models.GoalPeriod{}).Where("user_id = ? AND hit = ?", userID, true).Count(&hitCount).Error; err != nil {
+		utils.APILog("[GoalService.checkMilestones] ❌ 统计用户 %s 的目标达成次数失败: %v", userID, err)
+		return
+	}
+	for milestone, achievementType := range goalMilestones {
+		if hitCount >= milestone {
+			s.achievementService.Unlock(userID, achievementType)
+		}
+	}
+}
+
+// ClosePeriods 结算已结束的周期：写入最终完成量，未达成的周期记为未完成
+func (s *GoalService) ClosePeriods() error {
+	now := time.Now()
+	var goals []models.Goal
+	// 最短的周期为一天，开始时间在一天之内的目标不可能有已结束的周期
+	if err := s.db.Where("active = ? AND next_period_start <= ?", true, now.Add(-24*time.Hour)).Find(&goals).Error; err != nil {
+		return err
+	}
+
+	closed := 0
+	for i := range goals {
+		goal := &goals[i]
+		loc := userLocation(s.db, goal.UserID)
+		for n := 0; n < maxGoalPeriodsPerRun; n++ {
+			start := goal.NextPeriodStart
+			end := goalPeriodEnd(goal.Period, start, loc)
+			if end.After(now) {
+				break
+			}
+			if err := s.closePeriod(goal, start, end); err != nil {
+				utils.APILog("[GoalService.ClosePeriods] ❌ 结算目标 %s 的周期失败: %v", goal.ID, err)
+				break
+			}
+			goal.NextPeriodStart = end
+			closed++
+		}
+	}
+	if closed > 0 {
+		utils.APILog("[GoalService.ClosePeriods] 已结算 %d 个目标周期", closed)
+	}
+	return nil
+}
+
+// closePeriod 写入周期的最终结果并推进目标的待结算周期
+func (s *GoalService) closePeriod(goal *models.Goal, start, end time.Time) error {
+	achieved, err := s.achieved(goal, start, end)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 周期内已达成的保留达成时间，只更新最终完成量
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "goal_id"}, {Name: "period_start"}},
+			DoUpdates: clause.AssignmentColumns([]string{"achieved", "updated_at"}),
+		}).Create(&models.GoalPeriod{
+			GoalID:      goal.ID,
+			UserID:      goal.UserID,
+			PeriodStart: start,
+			PeriodEnd:   end,
+			Target:      goal.Target,
+			Achieved:    achieved,
+			Hit:         achieved >= goal.Target,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Goal{}).Where("id = ?", goal.ID).Update("next_period_start", end).Error
+	})
+}
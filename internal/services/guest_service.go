@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/validator"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotGuest             = errors.New("当前账号不是游客账号")
+	ErrGuestLimitExceeded   = errors.New("创建游客账号过于频繁，请稍后再试")
+	ErrIdentifierRegistered = errors.New("该邮箱或手机号已被注册")
+)
+
+// GuestService 游客账号：无需注册即可记录训练，之后可升级为正式账号
+type GuestService struct {
+	db                      *gorm.DB
+	cfg                     *config.Config
+	authService             *AuthService
+	verificationCodeService *VerificationCodeService
+	tokenService            *TokenService
+	accountService          *AccountService
+	audit                   *AuditService
+}
+
+func NewGuestService(db *gorm.DB, cfg *config.Config) *GuestService {
+	return &GuestService{
+		db:                      db,
+		cfg:                     cfg,
+		authService:             NewAuthService(db, cfg),
+		verificationCodeService: NewVerificationCodeService(db, cfg),
+		tokenService:            NewTokenService(db, cfg),
+		accountService:          NewAccountService(db, cfg),
+		audit:                   NewAuditService(db),
+	}
+}
+
+// Create 创建一个没有邮箱/手机号和密码的游客账号并签发令牌
+func (s *GuestService) Create(device DeviceInfo) (*models.User, *TokenPair, error) {
+	if s.cfg.Guest.IPHourlyLimit > 0 && device.IP != "" {
+		var count int64
+		if err := s.db.Model(&models.User{}).
+			Joins("JOIN user_sessions ON user_sessions.user_id = users.id").
+			Where("users.role = ? AND users.created_at > ? AND user_sessions.ip = ?", models.RoleGuest, time.Now().Add(-time.Hour), device.IP).
+			Distinct("users.id").
+			Count(&count).Error; err != nil {
+			return nil, nil, err
+		}
+		if count >= int64(s.cfg.Guest.IPHourlyLimit) {
+			return nil, nil, ErrGuestLimitExceeded
+		}
+	}
+
+	username, err := generateUsername(s.db, "游客")
+	if err != nil {
+		return nil, nil, err
+	}
+	user := models.User{
+		Username:  username,
+		AvatarURL: defaultAvatarURL(username),
+		Role:      models.RoleGuest,
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		return nil, nil, errors.New("创建游客账号失败")
+	}
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &user.ID,
+		Action:     models.AuditGuestCreate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID.String(),
+	})
+
+	tokens, err := s.tokenService.IssueTokens(user.ID, device)
+	if err != nil {
+		return nil, nil, errors.New("生成Token失败")
+	}
+	return &user, tokens, nil
+}
+
+// Upgrade 为游客账号绑定邮箱/手机号和密码，转为正式账号。训练记录等历史数据保持不变，
+// 已登录的会话继续有效。username 为空时保留原用户名
+func (s *GuestService) Upgrade(userID uuid.UUID, username, identifier, password, code string, device DeviceInfo) (*models.User, error) {
+	if !validator.IsEmailOrPhone(identifier) {
+		return nil, errors.New("邮箱或手机号格式不正确")
+	}
+	if !validator.ValidatePassword(password) {
+		return nil, errors.New("密码至少需要6个字符")
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	if user.Role != models.RoleGuest {
+		return nil, ErrNotGuest
+	}
+
+	if s.cfg.RequireRegisterCode {
+		if err := s.verificationCodeService.ValidateCode(identifier, code, "register"); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := findUserByIdentifier(s.db, identifier); err == nil {
+		return nil, ErrIdentifierRegistered
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"role": models.RoleUser}
+	if username != "" && username != user.Username {
+		var count int64
+		if err := s.db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.New("用户名已存在")
+		}
+		updates["username"] = username
+	}
+
+	passwordHash, err := s.authService.HashPassword(password)
+	if err != nil {
+		return nil, errors.New("密码加密失败")
+	}
+	updates["password_hash"] = passwordHash
+	if validator.IsEmail(identifier) {
+		updates["email"] = identifier
+	} else {
+		updates["phone"] = identifier
+	}
+
+	// 以 role 作为条件，避免并发请求重复升级
+	result := s.db.Model(&models.User{}).Where("id = ? AND role = ?", userID, models.RoleGuest).Updates(updates)
+	if result.Error != nil {
+		return nil, errors.New("升级账号失败")
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotGuest
+	}
+
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &user.ID,
+		Action:     models.AuditGuestUpgrade,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID.String(),
+	})
+	return &user, nil
+}
+
+// PurgeInactive 删除超过 GUEST_INACTIVE_TTL 没有任何活动（会话最近活跃时间）的游客账号
+func (s *GuestService) PurgeInactive() error {
+	if s.cfg.Guest.InactiveTTL <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-s.cfg.Guest.InactiveTTL)
+
+	var userIDs []uuid.UUID
+	if err := s.db.Model(&models.User{}).
+		Where("role = ? AND created_at < ?", models.RoleGuest, cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM user_sessions WHERE user_sessions.user_id = users.id AND user_sessions.last_seen_at >= ?)", cutoff).
+		Pluck("id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := s.accountService.deleteUser(userID); err != nil {
+			utils.APILog("[GuestService.PurgeInactive] ❌ 删除游客账号 %s 失败: %v", userID, err)
+			continue
+		}
+		s.audit.Record(DeviceInfo{}, models.AuditEvent{
+			Action:     models.AuditGuestPurged,
+			TargetType: models.AuditTargetUser,
+			TargetID:   userID.String(),
+		})
+	}
+	if len(userIDs) > 0 {
+		utils.APILog("[GuestService.PurgeInactive] 已清理 %d 个不活跃的游客账号", len(userIDs))
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/auth"
+	"fluent-life-backend/pkg/validator"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentifierUnchanged   = errors.New("新的邮箱或手机号与当前绑定的相同")
+	ErrInvalidRevertToken    = errors.New("撤销链接无效或已过期")
+	ErrIdentifierRevertStale = errors.New("绑定信息已再次变更或原邮箱/手机号已被占用，无法撤销")
+)
+
+// IdentifierChangeService 更换绑定的邮箱/手机号：先向新标识发送验证码，确认后替换，
+// 并通知原标识，原标识可以在撤销期限内恢复
+type IdentifierChangeService struct {
+	db                      *gorm.DB
+	cfg                     *config.Config
+	verificationCodeService *VerificationCodeService
+	tokenService            *TokenService
+	audit                   *AuditService
+}
+
+func NewIdentifierChangeService(db *gorm.DB, cfg *config.Config) *IdentifierChangeService {
+	return &IdentifierChangeService{
+		db:                      db,
+		cfg:                     cfg,
+		verificationCodeService: NewVerificationCodeService(db, cfg),
+		tokenService:            NewTokenService(db, cfg),
+		audit:                   NewAuditService(db),
+	}
+}
+
+// identifierField 返回标识对应的用户字段（email 或 phone）
+func identifierField(identifier string) (string, error) {
+	switch {
+	case validator.IsEmail(identifier):
+		return "email", nil
+	case validator.IsPhone(identifier):
+		return "phone", nil
+	default:
+		return "", errors.New("邮箱或手机号格式不正确")
+	}
+}
+
+func currentIdentifier(user *models.User, field string) *string {
+	if field == "email" {
+		return user.Email
+	}
+	return user.Phone
+}
+
+// maskIdentifier 隐藏邮箱/手机号中间部分，用于通知文案
+func maskIdentifier(identifier string) string {
+	if at := strings.Index(identifier, "@"); at > 0 {
+		if at <= 2 {
+			return identifier[:1] + "***" + identifier[at:]
+		}
+		return identifier[:2] + "***" + identifier[at:]
+	}
+	if len(identifier) >= 7 {
+		return identifier[:3] + "****" + identifier[len(identifier)-4:]
+	}
+	return identifier
+}
+
+// checkChangeAllowed 校验用户可以把 identifier 绑定到自己的账号上
+func (s *IdentifierChangeService) checkChangeAllowed(user *models.User, identifier string) (string, error) {
+	field, err := identifierField(identifier)
+	if err != nil {
+		return "", err
+	}
+	if user.Role == models.RoleGuest {
+		return "", errors.New("游客账号请先升级为正式账号")
+	}
+	if old := currentIdentifier(user, field); old != nil && *old == identifier {
+		return "", ErrIdentifierUnchanged
+	}
+	if existing, err := findUserByIdentifier(s.db, identifier); err == nil {
+		if existing.ID != user.ID {
+			return "", ErrIdentifierRegistered
+		}
+	} else if err != gorm.ErrRecordNotFound {
+		return "", err
+	}
+	return field, nil
+}
+
+// RequestChange 向新的邮箱/手机号发送验证码
+func (s *IdentifierChangeService) RequestChange(userID uuid.UUID, identifier, ip string) error {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if _, err := s.checkChangeAllowed(&user, identifier); err != nil {
+		return err
+	}
+	return s.verificationCodeService.SendCode(identifier, "change_identifier", ip)
+}
+
+// ConfirmChange 校验验证码后替换邮箱/手机号。原标识存在时会收到带撤销码的通知
+func (s *IdentifierChangeService) ConfirmChange(userID uuid.UUID, identifier, code string, device DeviceInfo) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	field, err := s.checkChangeAllowed(&user, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verificationCodeService.ValidateCode(identifier, code, "change_identifier"); err != nil {
+		return nil, err
+	}
+
+	change := models.IdentifierChange{
+		UserID:   userID,
+		Field:    field,
+		OldValue: currentIdentifier(&user, field),
+		NewValue: identifier,
+	}
+	var revertToken string
+	if change.OldValue != nil && s.cfg.IdentifierChangeRevertWindow > 0 {
+		revertToken, err = auth.GenerateOpaqueToken(32)
+		if err != nil {
+			return nil, errors.New("生成撤销码失败")
+		}
+		hash := auth.HashToken(revertToken)
+		expiresAt := time.Now().Add(s.cfg.IdentifierChangeRevertWindow)
+		change.RevertTokenHash = &hash
+		change.RevertExpiresAt = &expiresAt
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Where(field+" = ? AND id <> ?", identifier, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrIdentifierRegistered
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update(field, identifier).Error; err != nil {
+			return err
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrIdentifierRegistered) {
+			return nil, err
+		}
+		utils.APILog("[IdentifierChangeService.ConfirmChange] ❌ 用户 %s 更换%s失败: %v", userID, field, err)
+		return nil, errors.New("更换绑定失败")
+	}
+
+	if revertToken != "" {
+		s.verificationCodeService.SendNotice(*change.OldValue, "流畅生活账号绑定变更提醒", fmt.Sprintf(
+			"【流畅生活】您的账号已更换绑定为 %s。如非本人操作，请在%d天内使用撤销码 %s 恢复原绑定，撤销后所有设备需重新登录。",
+			maskIdentifier(identifier), int(s.cfg.IdentifierChangeRevertWindow.Hours()/24), revertToken))
+	}
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &userID,
+		Action:     models.AuditIdentifierChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID.String(),
+		Metadata:   models.JSONB{"field": field},
+	})
+
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Revert 凭撤销码恢复原邮箱/手机号，并吊销该用户的所有会话。
+// 只有在撤销期限内、当前值仍是这次变更的新值且原值未被他人占用时才能撤销
+func (s *IdentifierChangeService) Revert(token string, device DeviceInfo) (uuid.UUID, error) {
+	if token == "" {
+		return uuid.Nil, ErrInvalidRevertToken
+	}
+
+	var change models.IdentifierChange
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("revert_token_hash = ? AND reverted_at IS NULL AND revert_expires_at > ?", auth.HashToken(token), time.Now()).
+			First(&change).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidRevertToken
+			}
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.User{}).Where(change.Field+" = ? AND id <> ?", *change.OldValue, change.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrIdentifierRevertStale
+		}
+
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND "+change.Field+" = ?", change.UserID, change.NewValue).
+			Update(change.Field, *change.OldValue)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrIdentifierRevertStale
+		}
+
+		now := time.Now()
+		change.RevertedAt = &now
+		return tx.Model(&change).Update("reverted_at", now).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidRevertToken) || errors.Is(err, ErrIdentifierRevertStale) {
+			return uuid.Nil, err
+		}
+		utils.APILog("[IdentifierChangeService.Revert] ❌ 撤销绑定变更失败: %v", err)
+		return uuid.Nil, errors.New("撤销绑定变更失败")
+	}
+
+	if err := s.tokenService.RevokeAllSessions(change.UserID); err != nil {
+		utils.APILog("[IdentifierChangeService.Revert] ⚠️ 用户 %s 撤销绑定变更后吊销会话失败: %v", change.UserID, err)
+	}
+	s.audit.Record(device, models.AuditEvent{
+		Action:     models.AuditIdentifierRevert,
+		TargetType: models.AuditTargetUser,
+		TargetID:   change.UserID.String(),
+		Metadata:   models.JSONB{"field": change.Field, "change_id": change.ID.String()},
+	})
+	return change.UserID, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// RunPeriodically 在后台按固定间隔执行任务（启动时先执行一次），任务出错只记录日志
+func RunPeriodically(name string, interval time.Duration, fn func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						utils.APILog("[Job %s] ❌ 任务异常: %v", name, r)
+					}
+				}()
+				if err := fn(); err != nil {
+					utils.APILog("[Job %s] ❌ 执行失败: %v", name, err)
+				}
+			}()
+			<-ticker.C
+		}
+	}()
+}
+
+// StartBackgroundJobs 启动所有后台定时任务
+func StartBackgroundJobs(db *gorm.DB, cfg *config.Config) {
+	codeService := NewVerificationCodeService(db, cfg)
+	RunPeriodically("purge-verification-codes", time.Hour, codeService.PurgeExpired)
+
+	loginThrottle := NewLoginThrottleService(db, cfg)
+	RunPeriodically("purge-login-attempts", time.Hour, loginThrottle.PurgeStale)
+
+	oidcService := NewOIDCService(db, cfg)
+	RunPeriodically("purge-oauth-states", time.Hour, oidcService.PurgeExpiredStates)
+
+	accountService := NewAccountService(db, cfg)
+	RunPeriodically("process-account-deletions", time.Hour, accountService.ProcessDueDeletions)
+
+	guestService := NewGuestService(db, cfg)
+	RunPeriodically("purge-inactive-guests", time.Hour, guestService.PurgeInactive)
+
+	assignmentService := NewAssignmentService(db)
+	RunPeriodically("mark-overdue-assignments", 15*time.Minute, assignmentService.MarkOverdue)
+
+	goalService := NewGoalService(db)
+	RunPeriodically("close-goal-periods", 15*time.Minute, goalService.ClosePeriods)
+
+	auditService := NewAuditService(db)
+	if cfg.AuditRetention > 0 {
+		RunPeriodically("purge-audit-events", 24*time.Hour, func() error {
+			return auditService.PurgeBefore(time.Now().Add(-cfg.AuditRetention))
+		})
+	}
+
+	// 密钥目录中的新增、切换、移除无需重启即可生效
+	if cfg.JWTKeys.Dir != "" && cfg.JWTKeys.ReloadInterval > 0 {
+		RunPeriodically("reload-jwt-keys", cfg.JWTKeys.ReloadInterval, cfg.JWTKeySet.Reload)
+	}
+}
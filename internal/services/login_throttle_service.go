@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	attemptScopeIdentifier = "identifier"
+	attemptScopeIP         = "ip"
+
+	// 登录限制错误码，客户端据此展示倒计时
+	ErrCodeLoginThrottled = "LOGIN_THROTTLED"
+	ErrCodeAccountLocked  = "ACCOUNT_LOCKED"
+)
+
+// LoginThrottledError 登录被限制（退避或账号锁定）
+type LoginThrottledError struct {
+	Code       string    `json:"error_code"`
+	RetryAfter int64     `json:"retry_after"` // 距离可再次尝试的秒数
+	Until      time.Time `json:"until"`
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Code == ErrCodeAccountLocked {
+		return fmt.Sprintf("登录失败次数过多，账号已临时锁定，请%s后再试或重置密码", formatWait(e.RetryAfter))
+	}
+	return fmt.Sprintf("登录尝试过于频繁，请%s后再试", formatWait(e.RetryAfter))
+}
+
+func newLoginThrottledError(code string, until time.Time) *LoginThrottledError {
+	retryAfter := int64(math.Ceil(time.Until(until).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	return &LoginThrottledError{Code: code, RetryAfter: retryAfter, Until: until}
+}
+
+func formatWait(seconds int64) string {
+	if seconds < 60 {
+		return fmt.Sprintf("%d秒", seconds)
+	}
+	return fmt.Sprintf("%d分钟", (seconds+59)/60)
+}
+
+// LoginThrottleService 按账号标识和 IP 记录登录失败，实现指数退避和账号锁定
+type LoginThrottleService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewLoginThrottleService(db *gorm.DB, cfg *config.Config) *LoginThrottleService {
+	return &LoginThrottleService{db: db, cfg: cfg}
+}
+
+// Check 检查账号标识和 IP 当前是否处于退避期
+func (s *LoginThrottleService) Check(identifier, ip string) error {
+	var attempts []models.LoginAttempt
+	if err := s.db.Where("(scope = ? AND key = ?) OR (scope = ? AND key = ?)",
+		attemptScopeIdentifier, identifier, attemptScopeIP, ip).
+		Where("blocked_until > ?", time.Now()).
+		Find(&attempts).Error; err != nil {
+		return err
+	}
+
+	var until time.Time
+	for _, attempt := range attempts {
+		if attempt.BlockedUntil.After(until) {
+			until = *attempt.BlockedUntil
+		}
+	}
+	if !until.IsZero() {
+		return newLoginThrottledError(ErrCodeLoginThrottled, until)
+	}
+	return nil
+}
+
+// CheckUserLocked 检查账号是否被锁定
+func (s *LoginThrottleService) CheckUserLocked(user *models.User) error {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return newLoginThrottledError(ErrCodeAccountLocked, *user.LockedUntil)
+	}
+	return nil
+}
+
+// RecordFailure 记录一次登录失败；user 为空表示账号不存在（同样计数，避免暴露账号是否存在）
+func (s *LoginThrottleService) RecordFailure(identifier, ip string, user *models.User) {
+	limits := s.cfg.LoginLimits
+
+	failures, err := s.recordAttempt(attemptScopeIdentifier, identifier, limits.FreeAttempts)
+	if err != nil {
+		utils.APILog("[LoginThrottleService.RecordFailure] ⚠️ 记录登录失败出错: %v", err)
+		return
+	}
+	if ip != "" {
+		if _, err := s.recordAttempt(attemptScopeIP, ip, limits.IPFreeAttempts); err != nil {
+			utils.APILog("[LoginThrottleService.RecordFailure] ⚠️ 记录 IP 登录失败出错: %v", err)
+		}
+	}
+
+	if user != nil && limits.LockoutThreshold > 0 && failures >= limits.LockoutThreshold {
+		lockedUntil := time.Now().Add(limits.LockoutDuration)
+		if err := s.db.Model(user).Update("locked_until", lockedUntil).Error; err != nil {
+			utils.APILog("[LoginThrottleService.RecordFailure] ⚠️ 锁定账号 %s 失败: %v", user.ID, err)
+			return
+		}
+		user.LockedUntil = &lockedUntil
+		utils.APILog("[LoginThrottleService.RecordFailure] ⚠️ 账号 %s 连续登录失败 %d 次，锁定至 %s", user.ID, failures, lockedUntil.Format(time.RFC3339))
+	}
+}
+
+// recordAttempt 失败次数加一（超过计数窗口则重新计数），并计算退避截止时间，返回当前失败次数
+func (s *LoginThrottleService) recordAttempt(scope, key string, freeAttempts int) (int, error) {
+	now := time.Now()
+	windowStart := now.Add(-s.cfg.LoginLimits.AttemptWindow)
+
+	attempt := models.LoginAttempt{Scope: scope, Key: key, Failures: 1, LastFailedAt: now}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "scope"}, {Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":       gorm.Expr("CASE WHEN login_attempts.last_failed_at < ? THEN 1 ELSE login_attempts.failures + 1 END", windowStart),
+			"last_failed_at": now,
+		}),
+	}).Create(&attempt).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.db.Where("scope = ? AND key = ?", scope, key).First(&attempt).Error; err != nil {
+		return 0, err
+	}
+
+	if delay := s.backoff(attempt.Failures, freeAttempts); delay > 0 {
+		blockedUntil := now.Add(delay)
+		if err := s.db.Model(&attempt).Update("blocked_until", blockedUntil).Error; err != nil {
+			return 0, err
+		}
+	}
+	return attempt.Failures, nil
+}
+
+// backoff 超过免费次数后，每次失败的等待时间翻倍，直到上限
+func (s *LoginThrottleService) backoff(failures, freeAttempts int) time.Duration {
+	limits := s.cfg.LoginLimits
+	if failures < freeAttempts {
+		return 0
+	}
+	exponent := failures - freeAttempts
+	if exponent > 20 {
+		return limits.BackoffMax
+	}
+	delay := limits.BackoffBase * time.Duration(1<<uint(exponent))
+	if delay > limits.BackoffMax {
+		delay = limits.BackoffMax
+	}
+	return delay
+}
+
+// Reset 清除账号标识的失败记录（登录成功或重置密码后调用）
+func (s *LoginThrottleService) Reset(identifiers ...string) {
+	if len(identifiers) == 0 {
+		return
+	}
+	if err := s.db.Where("scope = ? AND key IN ?", attemptScopeIdentifier, identifiers).
+		Delete(&models.LoginAttempt{}).Error; err != nil {
+		utils.APILog("[LoginThrottleService.Reset] ⚠️ 清除登录失败记录出错: %v", err)
+	}
+}
+
+// Unlock 解除账号锁定并清除其邮箱、手机号的失败记录
+func (s *LoginThrottleService) Unlock(user *models.User) error {
+	if err := s.db.Model(user).Update("locked_until", nil).Error; err != nil {
+		return err
+	}
+	user.LockedUntil = nil
+
+	var identifiers []string
+	if user.Email != nil {
+		identifiers = append(identifiers, *user.Email)
+	}
+	if user.Phone != nil {
+		identifiers = append(identifiers, *user.Phone)
+	}
+	s.Reset(identifiers...)
+	return nil
+}
+
+// PurgeStale 清理超过计数窗口且不在退避期的记录
+func (s *LoginThrottleService) PurgeStale() error {
+	now := time.Now()
+	return s.db.Where("last_failed_at < ? AND (blocked_until IS NULL OR blocked_until < ?)",
+		now.Add(-s.cfg.LoginLimits.AttemptWindow), now).
+		Delete(&models.LoginAttempt{}).Error
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"time"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationService 站内通知
+type NotificationService struct {
+	db *gorm.DB
+}
+
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// Notify 给用户发送一条站内通知，失败只记录日志，不影响调用方
+func (s *NotificationService) Notify(userID uuid.UUID, notificationType, title, body string, data models.JSONB) {
+	notification := models.Notification{
+		UserID: userID,
+		Type:   notificationType,
+		Title:  title,
+		Body:   body,
+		Data:   data,
+	}
+	if err := s.db.Create(&notification).Error; err != nil {
+		utils.APILog("[NotificationService.Notify] ❌ 给用户 %s 发送通知 %s 失败: %v", userID, notificationType, err)
+	}
+}
+
+// List 分页获取通知（按时间倒序），同时返回未读数量
+func (s *NotificationService) List(userID uuid.UUID, unreadOnly bool, page, pageSize int) ([]models.Notification, int64, int64, error) {
+	query := s.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, 0, err
+	}
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	var unread int64
+	if err := s.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		return nil, 0, 0, err
+	}
+	return notifications, total, unread, nil
+}
+
+// MarkRead 将一条通知标记为已读
+func (s *NotificationService) MarkRead(userID, notificationID uuid.UUID) error {
+	var notification models.Notification
+	if err := s.db.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).Error; err != nil {
+		return err
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return s.db.Model(&notification).Update("read_at", time.Now()).Error
+}
+
+// MarkAllRead 将全部未读通知标记为已读
+func (s *NotificationService) MarkAllRead(userID uuid.UUID) error {
+	return s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now()).Error
+}
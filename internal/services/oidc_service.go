@@ -0,0 +1,408 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/oidc"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	oauthPurposeLogin = "login"
+	oauthPurposeLink  = "link"
+)
+
+var (
+	ErrOIDCProviderNotFound  = errors.New("不支持的登录方式")
+	ErrOIDCStateInvalid      = errors.New("授权请求无效或已过期，请重新发起")
+	ErrOIDCEmailInUse        = errors.New("该邮箱已注册，请使用原账号登录后在账号设置中绑定")
+	ErrIdentityAlreadyLinked = errors.New("该第三方账号已绑定其他用户")
+	ErrIdentityNotLinked     = errors.New("未绑定该第三方账号")
+	ErrLastLoginMethod       = errors.New("这是账号唯一的登录方式，请先设置密码或绑定手机号/邮箱")
+)
+
+// OIDCResult 回调处理结果：登录时返回用户和令牌，绑定时返回新建的身份
+type OIDCResult struct {
+	Purpose   string
+	User      *models.User
+	Tokens    *TokenPair
+	Challenge *TwoFactorChallenge // 用户开启了两步验证时返回挑战而不是令牌
+	Created   bool
+	Identity  *models.UserIdentity
+}
+
+// OIDCService 第三方 OIDC 登录与账号绑定
+type OIDCService struct {
+	db           *gorm.DB
+	cfg          *config.Config
+	tokenService *TokenService
+	twoFactor    *TwoFactorService
+	audit        *AuditService
+	providers    map[string]*oidc.Provider
+}
+
+func NewOIDCService(db *gorm.DB, cfg *config.Config) *OIDCService {
+	providers := make(map[string]*oidc.Provider)
+	for _, p := range cfg.OIDCProviders {
+		if p.Name == "" || p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			utils.APILog("[OIDCService] ⚠️ OIDC 提供方 %q 配置不完整，已忽略", p.Name)
+			continue
+		}
+		providers[p.Name] = oidc.NewProvider(p)
+	}
+
+	return &OIDCService{
+		db:           db,
+		cfg:          cfg,
+		tokenService: NewTokenService(db, cfg),
+		twoFactor:    NewTwoFactorService(db, cfg),
+		audit:        NewAuditService(db),
+		providers:    providers,
+	}
+}
+
+// Providers 已启用的提供方名称
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for _, p := range s.cfg.OIDCProviders {
+		if _, ok := s.providers[p.Name]; ok {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+func (s *OIDCService) provider(name string) (*oidc.Provider, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+	return p, nil
+}
+
+// BeginLogin 发起第三方登录，返回授权地址和 state
+func (s *OIDCService) BeginLogin(providerName string) (string, string, error) {
+	return s.begin(providerName, oauthPurposeLogin, nil)
+}
+
+// BeginLink 已登录用户发起第三方账号绑定
+func (s *OIDCService) BeginLink(userID uuid.UUID, providerName string) (string, string, error) {
+	return s.begin(providerName, oauthPurposeLink, &userID)
+}
+
+func (s *OIDCService) begin(providerName, purpose string, userID *uuid.UUID) (string, string, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString(48)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		return "", "", err
+	}
+
+	record := models.OAuthState{
+		State:        state,
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		Purpose:      purpose,
+		UserID:       userID,
+		ExpiresAt:    time.Now().Add(s.cfg.OIDCStateExpiration),
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// HandleCallback 处理授权回调：消费 state，换取并校验 ID Token，然后按发起时的用途登录或绑定
+func (s *OIDCService) HandleCallback(providerName, state, code string, device DeviceInfo) (*OIDCResult, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.consumeState(providerName, state)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := provider.Exchange(code, record.CodeVerifier)
+	if err != nil {
+		utils.APILog("[OIDCService.HandleCallback] ❌ %s 授权码换取令牌失败: %v", providerName, err)
+		return nil, errors.New("第三方授权失败")
+	}
+	claims, err := provider.VerifyIDToken(token.IDToken, record.Nonce)
+	if err != nil {
+		utils.APILog("[OIDCService.HandleCallback] ❌ %s ID Token 校验失败: %v", providerName, err)
+		return nil, errors.New("第三方授权失败")
+	}
+
+	if record.Purpose == oauthPurposeLink && record.UserID != nil {
+		identity, err := s.link(*record.UserID, providerName, claims)
+		if err != nil {
+			return nil, err
+		}
+		return &OIDCResult{Purpose: oauthPurposeLink, Identity: identity}, nil
+	}
+
+	user, created, err := s.loginUser(providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.twoFactor.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		challenge, err := s.twoFactor.IssueChallenge(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		s.audit.Record(device, models.AuditEvent{
+			ActorID:    &user.ID,
+			Action:     models.AuditTwoFactorRequired,
+			TargetType: models.AuditTargetUser,
+			TargetID:   user.ID.String(),
+			Metadata:   models.JSONB{"method": "oidc", "provider": providerName},
+		})
+		return &OIDCResult{Purpose: oauthPurposeLogin, User: user, Challenge: challenge}, nil
+	}
+
+	tokens, err := s.tokenService.IssueTokens(user.ID, device)
+	if err != nil {
+		if errors.Is(err, ErrUserSuspended) {
+			return nil, err
+		}
+		return nil, errors.New("生成Token失败")
+	}
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &user.ID,
+		Action:     models.AuditLogin,
+		TargetType: models.AuditTargetSession,
+		TargetID:   tokens.SessionID.String(),
+		Metadata:   models.JSONB{"method": "oidc", "provider": providerName, "new_user": created},
+	})
+	return &OIDCResult{Purpose: oauthPurposeLogin, User: user, Tokens: tokens, Created: created}, nil
+}
+
+// consumeState 一次性取出并删除 state，防止重放
+func (s *OIDCService) consumeState(providerName, state string) (*models.OAuthState, error) {
+	if state == "" {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	var record models.OAuthState
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state = ? AND provider = ?", state, providerName).First(&record).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil || time.Now().After(record.ExpiresAt) {
+		return nil, ErrOIDCStateInvalid
+	}
+	return &record, nil
+}
+
+// loginUser 根据第三方身份找到本地用户，没有则新建
+func (s *OIDCService) loginUser(providerName string, claims *oidc.IDTokenClaims) (*models.User, bool, error) {
+	now := time.Now()
+
+	var identity models.UserIdentity
+	err := s.db.Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&identity).Error
+	if err == nil {
+		var user models.User
+		if err := s.db.First(&user, "id = ?", identity.UserID).Error; err != nil {
+			return nil, false, err
+		}
+		s.db.Model(&identity).Update("last_login_at", now)
+		user.LastLoginAt = &now
+		s.db.Model(&user).Update("last_login_at", now)
+		return &user, false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, false, err
+	}
+
+	// 已验证的邮箱如果属于现有账号，不自动合并，避免账号被接管
+	var email *string
+	if claims.Email != "" && claims.EmailVerified {
+		var count int64
+		if err := s.db.Model(&models.User{}).Where("email = ?", claims.Email).Count(&count).Error; err != nil {
+			return nil, false, err
+		}
+		if count > 0 {
+			return nil, false, ErrOIDCEmailInUse
+		}
+		email = &claims.Email
+	}
+
+	username, err := generateUsername(s.db, usernamePrefix(claims))
+	if err != nil {
+		return nil, false, err
+	}
+
+	user := models.User{
+		Username:    username,
+		Email:       email,
+		AvatarURL:   defaultAvatarURL(username),
+		LastLoginAt: &now,
+	}
+	if claims.Picture != "" && len(claims.Picture) <= 500 {
+		user.AvatarURL = &claims.Picture
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Provider:    providerName,
+			Subject:     claims.Subject,
+			Email:       optionalString(claims.Email),
+			LastLoginAt: &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	utils.APILog("[OIDCService.loginUser] 通过 %s 登录创建了新用户 %s", providerName, user.ID)
+	return &user, true, nil
+}
+
+// link 将第三方身份绑定到已有用户
+func (s *OIDCService) link(userID uuid.UUID, providerName string, claims *oidc.IDTokenClaims) (*models.UserIdentity, error) {
+	var existing models.UserIdentity
+	err := s.db.Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&existing).Error
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return &existing, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.UserIdentity{}).Where("user_id = ? AND provider = ?", userID, providerName).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("已绑定该平台的其他账号，请先解绑")
+	}
+
+	identity := models.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    optionalString(claims.Email),
+	}
+	if err := s.db.Create(&identity).Error; err != nil {
+		return nil, err
+	}
+
+	utils.APILog("[OIDCService.link] 用户 %s 绑定了 %s 账号", userID, providerName)
+	return &identity, nil
+}
+
+// ListIdentities 获取用户已绑定的第三方账号
+func (s *OIDCService) ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	if err := s.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// Unlink 解绑第三方账号，不允许解绑账号唯一的登录方式
+func (s *OIDCService) Unlink(userID uuid.UUID, providerName string) error {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	var identity models.UserIdentity
+	if err := s.db.Where("user_id = ? AND provider = ?", userID, providerName).First(&identity).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrIdentityNotLinked
+		}
+		return err
+	}
+
+	var others int64
+	if err := s.db.Model(&models.UserIdentity{}).Where("user_id = ? AND id != ?", userID, identity.ID).Count(&others).Error; err != nil {
+		return err
+	}
+	if user.PasswordHash == "" && user.Email == nil && user.Phone == nil && others == 0 {
+		return ErrLastLoginMethod
+	}
+
+	if err := s.db.Delete(&identity).Error; err != nil {
+		return err
+	}
+	utils.APILog("[OIDCService.Unlink] 用户 %s 解绑了 %s 账号", userID, providerName)
+	return nil
+}
+
+// PurgeExpiredStates 清理过期的授权请求
+func (s *OIDCService) PurgeExpiredStates() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.OAuthState{}).Error
+}
+
+// usernamePrefix 用第三方昵称作为用户名前缀
+func usernamePrefix(claims *oidc.IDTokenClaims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name = claims.Name
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "用户"
+	}
+	for utf8.RuneCountInString(name) > 20 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name + "_"
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
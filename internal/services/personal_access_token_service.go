@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/auth"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenPrefix 个人访问令牌的固定前缀，认证中间件据此区分令牌类型
+const PersonalAccessTokenPrefix = "flp_"
+
+var (
+	ErrAccessTokenInvalid     = errors.New("访问令牌无效或已吊销")
+	ErrAccessTokenScopeDenied = errors.New("访问令牌缺少所需的权限范围")
+	ErrAccessTokenLimit       = errors.New("访问令牌数量已达上限，请先吊销不用的令牌")
+)
+
+// IsPersonalAccessToken 判断是否为个人访问令牌
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// PersonalAccessTokenService 个人访问令牌的创建、吊销和认证
+type PersonalAccessTokenService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewPersonalAccessTokenService(db *gorm.DB, cfg *config.Config) *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{db: db, cfg: cfg}
+}
+
+// Create 创建令牌，明文只在创建时返回一次；expiresIn 为 0 表示不过期
+func (s *PersonalAccessTokenService) Create(userID uuid.UUID, name string, scopes []string, expiresIn time.Duration) (*models.PersonalAccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 100 {
+		return nil, "", errors.New("令牌名称不能为空且不超过100个字符")
+	}
+	if len(scopes) == 0 {
+		return nil, "", errors.New("至少需要一个权限范围")
+	}
+	unique := make(models.StringList, 0, len(scopes))
+	for _, scope := range scopes {
+		if !models.IsValidScope(scope) {
+			return nil, "", errors.New("无效的权限范围: " + scope)
+		}
+		if !unique.Contains(scope) {
+			unique = append(unique, scope)
+		}
+	}
+
+	var count int64
+	if err := s.db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Count(&count).Error; err != nil {
+		return nil, "", err
+	}
+	if s.cfg.PersonalAccessTokenLimit > 0 && count >= int64(s.cfg.PersonalAccessTokenLimit) {
+		return nil, "", ErrAccessTokenLimit
+	}
+
+	secret, err := auth.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := PersonalAccessTokenPrefix + secret
+
+	token := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: auth.HashToken(plaintext),
+		Prefix:    plaintext[:len(PersonalAccessTokenPrefix)+6],
+		Scopes:    unique,
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.db.Create(&token).Error; err != nil {
+		return nil, "", err
+	}
+
+	utils.APILog("[PersonalAccessTokenService.Create] 用户 %s 创建了访问令牌 %s（%s）", userID, token.ID, strings.Join(unique, ","))
+	return &token, plaintext, nil
+}
+
+// List 获取用户的全部令牌（含已吊销、已过期），按创建时间倒序
+func (s *PersonalAccessTokenService) List(userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Revoke 吊销令牌
+func (s *PersonalAccessTokenService) Revoke(userID, tokenID uuid.UUID) error {
+	result := s.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	utils.APILog("[PersonalAccessTokenService.Revoke] 用户 %s 吊销了访问令牌 %s", userID, tokenID)
+	return nil
+}
+
+// Authenticate 校验令牌并返回令牌记录和所属用户的角色，同时更新最近使用时间
+func (s *PersonalAccessTokenService) Authenticate(plaintext, ip string) (*models.PersonalAccessToken, string, error) {
+	var token models.PersonalAccessToken
+	if err := s.db.Where("token_hash = ?", auth.HashToken(plaintext)).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, "", ErrAccessTokenInvalid
+		}
+		return nil, "", err
+	}
+	now := time.Now()
+	if !token.IsActive(now) {
+		return nil, "", ErrAccessTokenInvalid
+	}
+
+	var user models.User
+	if err := s.db.Select("id", "role", "suspended_at", "suspended_until").
+		First(&user, "id = ?", token.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, "", ErrAccessTokenInvalid
+		}
+		return nil, "", err
+	}
+	if user.IsSuspended(now) {
+		return nil, "", ErrUserSuspended
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastSeenInterval {
+		if err := s.db.Model(&token).Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error; err != nil {
+			utils.APILog("[PersonalAccessTokenService.Authenticate] ⚠️ 更新令牌使用时间失败: %v", err)
+		}
+	}
+
+	return &token, user.Role, nil
+}
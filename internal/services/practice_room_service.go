@@ -10,11 +10,12 @@ import (
 )
 
 type PracticeRoomService struct {
-	db *gorm.DB
+	db           *gorm.DB
+	blockService *BlockService
 }
 
 func NewPracticeRoomService(db *gorm.DB) *PracticeRoomService {
-	return &PracticeRoomService{db: db}
+	return &PracticeRoomService{db: db, blockService: NewBlockService(db)}
 }
 
 // CreateRoom 创建对练房
@@ -101,6 +102,17 @@ func (s *PracticeRoomService) JoinRoom(roomID, userID uuid.UUID) error {
 		return gorm.ErrRecordNotFound
 	}
 
+	// 被房主拉黑的用户不能加入
+	if room.UserID != userID {
+		blocked, err := s.blockService.IsBlocked(room.UserID, userID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrBlockedByHost
+		}
+	}
+
 	if room.CurrentMembers >= room.MaxMembers {
 		return gorm.ErrRecordNotFound // 房间已满
 	}
@@ -198,6 +210,28 @@ func (s *PracticeRoomService) LeaveRoom(roomID, userID uuid.UUID) error {
 	return s.db.Save(&room).Error
 }
 
+// CloseRoom 强制关闭房间（管理员/版主操作），清空成员
+func (s *PracticeRoomService) CloseRoom(roomID uuid.UUID) (*models.PracticeRoom, error) {
+	var room models.PracticeRoom
+	if err := s.db.First(&room, "id = ?", roomID).Error; err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("room_id = ?", roomID).Delete(&models.PracticeRoomMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&room).Updates(map[string]interface{}{
+			"is_active":       false,
+			"current_members": 0,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
 // TransferHost 转移房主
 func (s *PracticeRoomService) TransferHost(roomID, newHostUserID uuid.UUID) error {
 	// 取消所有成员的房主身份
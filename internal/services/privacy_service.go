@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrProfileHidden          = errors.New("该用户设置了主页不公开")
+	ErrCommentNotAllowed      = errors.New("作者设置了不允许你评论")
+	ErrInvalidPrivacySettings = errors.New("无效的隐私设置")
+)
+
+// notHiddenFromPartnersSQL 过滤掉设置了不出现在学习伙伴/匹配中的用户（用于 users 表查询）
+const notHiddenFromPartnersSQL = "NOT EXISTS (SELECT 1 FROM privacy_settings ps WHERE ps.user_id = users.id AND ps.hide_from_partners)"
+
+// PrivacyUpdate 隐私设置的部分更新，nil 字段保持不变
+type PrivacyUpdate struct {
+	ProfileVisibility *string `json:"profile_visibility"`
+	HideTrainingStats *bool   `json:"hide_training_stats"`
+	HideFromPartners  *bool   `json:"hide_from_partners"`
+	CommentPermission *string `json:"comment_permission"`
+}
+
+// PrivacyService 用户隐私设置的读写与校验
+type PrivacyService struct {
+	db            *gorm.DB
+	followService *FollowService
+}
+
+func NewPrivacyService(db *gorm.DB) *PrivacyService {
+	return &PrivacyService{
+		db:            db,
+		followService: NewFollowService(db),
+	}
+}
+
+// Get 获取用户的隐私设置，未设置过时返回默认值
+func (s *PrivacyService) Get(userID uuid.UUID) (*models.PrivacySettings, error) {
+	var settings models.PrivacySettings
+	err := s.db.Where("user_id = ?", userID).First(&settings).Error
+	if err == gorm.ErrRecordNotFound {
+		settings = models.DefaultPrivacySettings(userID)
+		return &settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Update 修改隐私设置
+func (s *PrivacyService) Update(userID uuid.UUID, update PrivacyUpdate) (*models.PrivacySettings, error) {
+	settings, err := s.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.ProfileVisibility != nil {
+		if !models.IsValidProfileVisibility(*update.ProfileVisibility) {
+			return nil, ErrInvalidPrivacySettings
+		}
+		settings.ProfileVisibility = *update.ProfileVisibility
+	}
+	if update.CommentPermission != nil {
+		if !models.IsValidCommentPermission(*update.CommentPermission) {
+			return nil, ErrInvalidPrivacySettings
+		}
+		settings.CommentPermission = *update.CommentPermission
+	}
+	if update.HideTrainingStats != nil {
+		settings.HideTrainingStats = *update.HideTrainingStats
+	}
+	if update.HideFromPartners != nil {
+		settings.HideFromPartners = *update.HideFromPartners
+	}
+
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"profile_visibility", "hide_training_stats", "hide_from_partners", "comment_permission", "updated_at",
+		}),
+	}).Create(settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// ProfileAccess 判断 viewerID（未登录为 uuid.Nil）查看 ownerID 的主页时能否看到主页和训练统计
+func (s *PrivacyService) ProfileAccess(viewerID, ownerID uuid.UUID) (profile, stats bool, err error) {
+	if viewerID == ownerID {
+		return true, true, nil
+	}
+	if viewerID != uuid.Nil {
+		blocked, err := isBlockedEither(s.db, viewerID, ownerID)
+		if err != nil || blocked {
+			return false, false, err
+		}
+	}
+	settings, err := s.Get(ownerID)
+	if err != nil {
+		return false, false, err
+	}
+
+	switch settings.ProfileVisibility {
+	case models.ProfileVisibilityPublic:
+		profile = true
+	case models.ProfileVisibilityFollowers:
+		if viewerID != uuid.Nil {
+			if profile, err = s.followService.IsFollowing(viewerID, ownerID); err != nil {
+				return false, false, err
+			}
+		}
+	}
+	return profile, profile && !settings.HideTrainingStats, nil
+}
+
+// CanComment 判断 commenterID 能否评论 ownerID 发布的帖子
+func (s *PrivacyService) CanComment(commenterID, ownerID uuid.UUID) (bool, error) {
+	if commenterID == ownerID {
+		return true, nil
+	}
+	blocked, err := isBlockedEither(s.db, commenterID, ownerID)
+	if err != nil || blocked {
+		return false, err
+	}
+	settings, err := s.Get(ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	switch settings.CommentPermission {
+	case models.CommentPermissionEveryone:
+		return true, nil
+	case models.CommentPermissionFollowers:
+		return s.followService.IsFollowing(commenterID, ownerID)
+	default:
+		return false, nil
+	}
+}
+
+// CanBeMatched 判断两个用户能否被 1v1 匹配到一起（双方都没有隐藏自己、也没有拉黑关系），
+// 供 hub.RoomHub.MatchFilter 使用。查询失败时不匹配
+func (s *PrivacyService) CanBeMatched(userA, userB string) bool {
+	a, errA := uuid.Parse(userA)
+	b, errB := uuid.Parse(userB)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	var hidden int64
+	if err := s.db.Model(&models.PrivacySettings{}).
+		Where("user_id IN ? AND hide_from_partners", []uuid.UUID{a, b}).
+		Count(&hidden).Error; err != nil {
+		utils.APILog("[PrivacyService.CanBeMatched] ❌ 查询隐私设置失败: %v", err)
+		return false
+	}
+	if hidden > 0 {
+		return false
+	}
+
+	blocked, err := isBlockedEither(s.db, a, b)
+	if err != nil {
+		utils.APILog("[PrivacyService.CanBeMatched] ❌ 查询拉黑关系失败: %v", err)
+		return false
+	}
+	return !blocked
+}
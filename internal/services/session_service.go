@@ -0,0 +1,58 @@
+package services
+
+import (
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// lastSeenInterval 最近活跃时间的最小更新间隔，避免每个请求都写库
+const lastSeenInterval = time.Minute
+
+type SessionService struct {
+	db           *gorm.DB
+	tokenService *TokenService
+}
+
+func NewSessionService(db *gorm.DB, cfg *config.Config) *SessionService {
+	return &SessionService{
+		db:           db,
+		tokenService: NewTokenService(db, cfg),
+	}
+}
+
+// GetActiveSessions 获取用户当前有效的登录会话，按最近活跃时间倒序
+func (s *SessionService) GetActiveSessions(userID, currentSessionID uuid.UUID) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].IsCurrent = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession 结束用户的某个会话
+func (s *SessionService) RevokeSession(userID, sessionID uuid.UUID) error {
+	var session models.UserSession
+	if err := s.db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		return err // gorm.ErrRecordNotFound if not found
+	}
+	return s.tokenService.RevokeSession(userID, sessionID)
+}
+
+// Touch 更新会话的最近活跃时间和 IP
+func (s *SessionService) Touch(sessionID uuid.UUID, ip string) error {
+	now := time.Now()
+	return s.db.Model(&models.UserSession{}).
+		Where("id = ? AND last_seen_at < ?", sessionID, now.Add(-lastSeenInterval)).
+		Updates(map[string]interface{}{"last_seen_at": now, "ip": ip}).Error
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	streakFreezeEarnDays = 7 // 每连续练习7天获得一次冻结
+	maxStreakFreezes     = 2 // 最多同时持有的冻结次数
+)
+
+// streakMilestones 连续练习天数里程碑，达到后解锁 streak_<天数> 成就
+var streakMilestones = []int{7, 30, 100}
+
+// StreakService 连续练习天数：按用户当地日期统计，漏练的日子自动消耗冻结次数保护连续记录
+type StreakService struct {
+	db                 *gorm.DB
+	achievementService *AchievementService
+}
+
+func NewStreakService(db *gorm.DB) *StreakService {
+	return &StreakService{
+		db:                 db,
+		achievementService: NewAchievementService(db),
+	}
+}
+
+// Streak 用户的连续练习情况
+type Streak struct {
+	Current         int      `json:"current"` // 当前连续天数（今天还没练习时截至昨天）
+	Longest         int      `json:"longest"` // 历史最长连续天数
+	PracticedToday  bool     `json:"practiced_today"`
+	FreezeTokens    int      `json:"freeze_tokens"` // 当前持有的冻结次数
+	MaxFreezeTokens int      `json:"max_freeze_tokens"`
+	NextFreezeIn    int      `json:"next_freeze_in"` // 再连续练习几天获得下一次冻结
+	FrozenDates     []string `json:"frozen_dates"`   // 当前连续记录中被冻结保护的日期
+	NextMilestone   *int     `json:"next_milestone,omitempty"`
+	Timezone        string   `json:"timezone"`
+}
+
+// practiceDays 用户有训练记录的当地日期（升序，以 UTC 零点表示）
+func (s *StreakService) practiceDays(userID uuid.UUID, loc *time.Location) ([]time.Time, error) {
+	var days []time.Time
+	err := s.db.Model(&models.TrainingRecord{}).
+		Where("user_id = ?", userID).
+		Distinct(localDateSQL+" AS day", loc.String()).
+		Order("day ASC").
+		Pluck("day", &days).Error
+	return days, err
+}
+
+// localDate 以 UTC 零点表示 t 在 loc 时区的日期，便于逐日比较
+func localDate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Get 从第一天练习开始逐日回放：练习日计入连续天数并累计冻结次数，
+// 漏练的日子（不含今天）有冻结次数时自动消耗一次保护连续记录，否则连续记录中断
+func (s *StreakService) Get(userID uuid.UUID) (*Streak, error) {
+	loc := userLocation(s.db, userID)
+	days, err := s.practiceDays(userID, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	streak := &Streak{MaxFreezeTokens: maxStreakFreezes, FrozenDates: []string{}, Timezone: loc.String()}
+	today := localDate(time.Now(), loc)
+	practiced := make(map[time.Time]bool, len(days))
+	for _, day := range days {
+		practiced[day.UTC()] = true
+	}
+
+	sinceEarn := 0
+	if len(days) > 0 {
+		for day := days[0].UTC(); !day.After(today); day = day.AddDate(0, 0, 1) {
+			switch {
+			case practiced[day]:
+				streak.Current++
+				sinceEarn++
+				if sinceEarn == streakFreezeEarnDays {
+					sinceEarn = 0
+					if streak.FreezeTokens < maxStreakFreezes {
+						streak.FreezeTokens++
+					}
+				}
+				if streak.Current > streak.Longest {
+					streak.Longest = streak.Current
+				}
+			case day.Equal(today):
+				// 今天还没练习，不算中断
+			case streak.Current > 0 && streak.FreezeTokens > 0:
+				streak.FreezeTokens--
+				streak.FrozenDates = append(streak.FrozenDates, day.Format("2006-01-02"))
+			default:
+				streak.Current = 0
+				sinceEarn = 0
+				streak.FrozenDates = []string{}
+			}
+		}
+	}
+
+	streak.PracticedToday = practiced[today]
+	streak.NextFreezeIn = streakFreezeEarnDays - sinceEarn
+	for _, milestone := range streakMilestones {
+		if streak.Longest < milestone {
+			next := milestone
+			streak.NextMilestone = &next
+			break
+		}
+	}
+	return streak, nil
+}
+
+// CheckMilestones 解锁已达到的连续天数成就，失败只记录日志
+func (s *StreakService) CheckMilestones(userID uuid.UUID) {
+	streak, err := s.Get(userID)
+	if err != nil {
+		utils.APILog("[StreakService.CheckMilestones] ❌ 计算用户 %s 的连续天数失败: %v", userID, err)
+		return
+	}
+	for _, milestone := range streakMilestones {
+		if streak.Longest >= milestone {
+			s.achievementService.Unlock(userID, fmt.Sprintf("streak_%d", milestone))
+		}
+	}
+}
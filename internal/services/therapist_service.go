@@ -0,0 +1,395 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/auth"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInviteInvalid       = errors.New("邀请链接无效或已过期")
+	ErrAlreadyLinked       = errors.New("已与该治疗师建立关联")
+	ErrClientNotLinked     = errors.New("该用户不是你的客户或已撤销授权")
+	ErrConsentNotGranted   = errors.New("客户未授权查看该数据")
+	ErrInvalidConsentScope = errors.New("无效的授权范围")
+)
+
+// TherapistService 治疗师与客户的关联：邀请、授权、撤销，以及按授权范围查看客户数据
+type TherapistService struct {
+	db              *gorm.DB
+	cfg             *config.Config
+	trainingService *TrainingService
+	aiService       *AIService
+	audit           *AuditService
+}
+
+func NewTherapistService(db *gorm.DB, cfg *config.Config) *TherapistService {
+	return &TherapistService{
+		db:              db,
+		cfg:             cfg,
+		trainingService: NewTrainingService(db, cfg),
+		aiService:       NewAIService(db, cfg),
+		audit:           NewAuditService(db),
+	}
+}
+
+// TherapistClient 治疗师看到的客户概况，训练统计只在客户授权 training_records 时返回
+type TherapistClient struct {
+	LinkID        uuid.UUID                `json:"link_id"`
+	Client        *models.User             `json:"client"`
+	Scopes        models.StringList        `json:"scopes"`
+	LinkedAt      *time.Time               `json:"linked_at"`
+	Stats         map[string]interface{}   `json:"stats,omitempty"`
+	WeeklyStats   []map[string]interface{} `json:"weekly_stats,omitempty"`
+	ProgressTrend []ProgressTrendData      `json:"progress_trend,omitempty"`
+}
+
+// normalizeConsentScopes 校验并去重授权范围
+func normalizeConsentScopes(scopes []string) (models.StringList, error) {
+	unique := make(models.StringList, 0, len(scopes))
+	for _, scope := range scopes {
+		if !models.IsValidConsentScope(scope) {
+			return nil, ErrInvalidConsentScope
+		}
+		if !unique.Contains(scope) {
+			unique = append(unique, scope)
+		}
+	}
+	return unique, nil
+}
+
+func preloadBasicUser(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "username", "avatar_url")
+}
+
+// CreateInvite 治疗师创建邀请，邀请码明文只返回一次；scopes 为空时申请全部范围
+func (s *TherapistService) CreateInvite(therapistID uuid.UUID, scopes []string, note string) (*models.TherapistLink, string, error) {
+	if len(scopes) == 0 {
+		scopes = models.ConsentScopes
+	}
+	requested, err := normalizeConsentScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	note = strings.TrimSpace(note)
+	if len([]rune(note)) > 200 {
+		return nil, "", errors.New("邀请附言不能超过200个字符")
+	}
+
+	token, err := auth.GenerateOpaqueToken(24)
+	if err != nil {
+		return nil, "", err
+	}
+	link := models.TherapistLink{
+		TherapistID:     therapistID,
+		Status:          models.TherapistLinkPending,
+		Note:            note,
+		InviteTokenHash: auth.HashToken(token),
+		InviteExpiresAt: time.Now().Add(s.cfg.TherapistInviteTTL),
+		RequestedScopes: requested,
+		Scopes:          models.StringList{},
+	}
+	if err := s.db.Create(&link).Error; err != nil {
+		return nil, "", err
+	}
+
+	utils.APILog("[TherapistService.CreateInvite] 治疗师 %s 创建了邀请 %s（%s）", therapistID, link.ID, strings.Join(requested, ","))
+	return &link, token, nil
+}
+
+// ListInvites 治疗师尚未被接受且未过期的邀请
+func (s *TherapistService) ListInvites(therapistID uuid.UUID) ([]models.TherapistLink, error) {
+	var links []models.TherapistLink
+	err := s.db.Where("therapist_id = ? AND status = ? AND invite_expires_at > ?", therapistID, models.TherapistLinkPending, time.Now()).
+		Order("created_at DESC").Find(&links).Error
+	return links, err
+}
+
+// CancelInvite 治疗师取消尚未被接受的邀请
+func (s *TherapistService) CancelInvite(therapistID, linkID uuid.UUID) error {
+	result := s.db.Model(&models.TherapistLink{}).
+		Where("id = ? AND therapist_id = ? AND status = ?", linkID, therapistID, models.TherapistLinkPending).
+		Updates(map[string]interface{}{"status": models.TherapistLinkRevoked, "revoked_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// findInvite 查找有效的邀请，并确认发出邀请的用户仍是治疗师
+func (s *TherapistService) findInvite(db *gorm.DB, token string) (*models.TherapistLink, error) {
+	var link models.TherapistLink
+	err := db.Preload("Therapist", preloadBasicUser).
+		Joins("JOIN users ON users.id = therapist_links.therapist_id AND users.role = ?", models.RoleTherapist).
+		Where("therapist_links.invite_token_hash = ? AND therapist_links.status = ? AND therapist_links.invite_expires_at > ?",
+			auth.HashToken(token), models.TherapistLinkPending, time.Now()).
+		First(&link).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrInviteInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// PreviewInvite 客户接受前查看邀请：发出邀请的治疗师和申请的数据范围
+func (s *TherapistService) PreviewInvite(token string) (*models.TherapistLink, error) {
+	return s.findInvite(s.db, token)
+}
+
+// AcceptInvite 客户接受邀请。scopes 为客户同意的范围，必须是申请范围的子集，
+// 为 nil 时同意全部申请范围
+func (s *TherapistService) AcceptInvite(clientID uuid.UUID, token string, scopes []string, device DeviceInfo) (*models.TherapistLink, error) {
+	var link *models.TherapistLink
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		link, err = s.findInvite(tx, token)
+		if err != nil {
+			return err
+		}
+		if link.TherapistID == clientID {
+			return errors.New("不能接受自己发出的邀请")
+		}
+
+		granted := link.RequestedScopes
+		if scopes != nil {
+			if granted, err = normalizeConsentScopes(scopes); err != nil {
+				return err
+			}
+			for _, scope := range granted {
+				if !link.RequestedScopes.Contains(scope) {
+					return errors.New("授权范围超出了治疗师申请的范围: " + scope)
+				}
+			}
+		}
+
+		var count int64
+		if err := tx.Model(&models.TherapistLink{}).
+			Where("therapist_id = ? AND client_id = ? AND status = ?", link.TherapistID, clientID, models.TherapistLinkActive).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyLinked
+		}
+
+		now := time.Now()
+		// 以 status 作为条件，避免同一邀请被多人接受
+		result := tx.Model(&models.TherapistLink{}).
+			Where("id = ? AND status = ?", link.ID, models.TherapistLinkPending).
+			Updates(map[string]interface{}{
+				"client_id":   clientID,
+				"status":      models.TherapistLinkActive,
+				"scopes":      granted,
+				"accepted_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInviteInvalid
+		}
+		link.ClientID = &clientID
+		link.Status = models.TherapistLinkActive
+		link.Scopes = granted
+		link.AcceptedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &clientID,
+		Action:     models.AuditTherapistLink,
+		TargetType: models.AuditTargetUser,
+		TargetID:   link.TherapistID.String(),
+		Metadata:   models.JSONB{"link_id": link.ID.String(), "scopes": []string(link.Scopes)},
+	})
+	return link, nil
+}
+
+// ListTherapists 客户当前关联的治疗师
+func (s *TherapistService) ListTherapists(clientID uuid.UUID) ([]models.TherapistLink, error) {
+	var links []models.TherapistLink
+	err := s.db.Preload("Therapist", preloadBasicUser).
+		Where("client_id = ? AND status = ?", clientID, models.TherapistLinkActive).
+		Order("accepted_at DESC").Find(&links).Error
+	return links, err
+}
+
+// UpdateConsent 客户修改授权范围，scopes 为空表示保留关联但不授权查看任何数据
+func (s *TherapistService) UpdateConsent(clientID, linkID uuid.UUID, scopes []string, device DeviceInfo) (*models.TherapistLink, error) {
+	granted, err := normalizeConsentScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	var link models.TherapistLink
+	if err := s.db.Where("id = ? AND client_id = ? AND status = ?", linkID, clientID, models.TherapistLinkActive).First(&link).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&link).Update("scopes", granted).Error; err != nil {
+		return nil, err
+	}
+	link.Scopes = granted
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &clientID,
+		Action:     models.AuditTherapistConsentUpdate,
+		TargetType: models.AuditTargetUser,
+		TargetID:   link.TherapistID.String(),
+		Metadata:   models.JSONB{"link_id": link.ID.String(), "scopes": []string(granted)},
+	})
+	return &link, nil
+}
+
+// Revoke 客户撤销对治疗师的全部授权并解除关联
+func (s *TherapistService) Revoke(clientID, linkID uuid.UUID, device DeviceInfo) error {
+	var link models.TherapistLink
+	if err := s.db.Where("id = ? AND client_id = ? AND status = ?", linkID, clientID, models.TherapistLinkActive).First(&link).Error; err != nil {
+		return err
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&link).Updates(map[string]interface{}{
+			"status":     models.TherapistLinkRevoked,
+			"scopes":     models.StringList{},
+			"revoked_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		// 解除关联后该治疗师布置的未完成作业一并取消
+		return tx.Model(&models.Assignment{}).
+			Where("therapist_id = ? AND client_id = ? AND status = ?", link.TherapistID, clientID, models.AssignmentActive).
+			Update("status", models.AssignmentCancelled).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(device, models.AuditEvent{
+		ActorID:    &clientID,
+		Action:     models.AuditTherapistRevoke,
+		TargetType: models.AuditTargetUser,
+		TargetID:   link.TherapistID.String(),
+		Metadata:   models.JSONB{"link_id": link.ID.String()},
+	})
+	return nil
+}
+
+// findActiveLink 获取治疗师与客户之间生效中的关联，不存在时返回 ErrClientNotLinked
+func findActiveLink(db *gorm.DB, therapistID, clientID uuid.UUID) (*models.TherapistLink, error) {
+	var link models.TherapistLink
+	err := db.Preload("Client", preloadBasicUser).
+		Where("therapist_id = ? AND client_id = ? AND status = ?", therapistID, clientID, models.TherapistLinkActive).
+		First(&link).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrClientNotLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// clientLink 获取治疗师与客户之间生效中的关联；scope 不为空时还要求客户授权了该范围
+func (s *TherapistService) clientLink(therapistID, clientID uuid.UUID, scope string) (*models.TherapistLink, error) {
+	link, err := findActiveLink(s.db, therapistID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if scope != "" && !link.Allows(scope) {
+		return nil, ErrConsentNotGranted
+	}
+	return link, nil
+}
+
+// clientSummary 汇总客户概况，未授权 training_records 时不包含训练统计
+func (s *TherapistService) clientSummary(link *models.TherapistLink) (*TherapistClient, error) {
+	client := &TherapistClient{
+		LinkID:   link.ID,
+		Client:   link.Client,
+		Scopes:   link.Scopes,
+		LinkedAt: link.AcceptedAt,
+	}
+	if !link.Allows(models.ConsentTrainingRecords) {
+		return client, nil
+	}
+
+	var err error
+	if client.Stats, err = s.trainingService.GetStats(*link.ClientID); err != nil {
+		return nil, err
+	}
+	if client.WeeklyStats, err = s.trainingService.GetWeeklyStats(*link.ClientID); err != nil {
+		return nil, err
+	}
+	if client.ProgressTrend, err = s.trainingService.GetProgressTrend(*link.ClientID); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// ListClients 治疗师的全部客户及其训练统计
+func (s *TherapistService) ListClients(therapistID uuid.UUID) ([]TherapistClient, error) {
+	var links []models.TherapistLink
+	if err := s.db.Preload("Client", preloadBasicUser).
+		Where("therapist_id = ? AND status = ?", therapistID, models.TherapistLinkActive).
+		Order("accepted_at DESC").Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	clients := make([]TherapistClient, 0, len(links))
+	for i := range links {
+		client, err := s.clientSummary(&links[i])
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, *client)
+	}
+	return clients, nil
+}
+
+// GetClient 单个客户的概况
+func (s *TherapistService) GetClient(therapistID, clientID uuid.UUID) (*TherapistClient, error) {
+	link, err := s.clientLink(therapistID, clientID, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.clientSummary(link)
+}
+
+// GetClientRecords 客户的训练记录（需要 training_records 授权）
+func (s *TherapistService) GetClientRecords(therapistID, clientID uuid.UUID, page, pageSize int, recordType string) ([]models.TrainingRecord, int64, error) {
+	if _, err := s.clientLink(therapistID, clientID, models.ConsentTrainingRecords); err != nil {
+		return nil, 0, err
+	}
+	return s.trainingService.GetRecords(clientID, page, pageSize, recordType)
+}
+
+// GetClientMeditationProgress 客户的冥想进度（需要 meditation_progress 授权）
+func (s *TherapistService) GetClientMeditationProgress(therapistID, clientID uuid.UUID) (map[string]interface{}, error) {
+	if _, err := s.clientLink(therapistID, clientID, models.ConsentMeditationProgress); err != nil {
+		return nil, err
+	}
+	return s.trainingService.GetMeditationProgress(clientID)
+}
+
+// GetClientAIConversation 客户与 AI 导师的对话及分析（需要 ai_analysis 授权）
+func (s *TherapistService) GetClientAIConversation(therapistID, clientID uuid.UUID) (*models.AIConversation, error) {
+	if _, err := s.clientLink(therapistID, clientID, models.ConsentAIAnalysis); err != nil {
+		return nil, err
+	}
+	return s.aiService.GetConversation(clientID)
+}
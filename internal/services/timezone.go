@@ -0,0 +1,37 @@
+package services
+
+import (
+	"time"
+
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// userLocation 用户设置的时区，未设置时使用默认时区。按天统计时都以用户当地的日历为准
+func userLocation(db *gorm.DB, userID uuid.UUID) *time.Location {
+	var timezone string
+	if err := db.Model(&models.User{}).Where("id = ?", userID).Select("timezone").Scan(&timezone).Error; err != nil {
+		utils.APILog("[userLocation] ⚠️ 读取用户 %s 的时区失败: %v", userID, err)
+	}
+	return utils.LoadLocation(timezone)
+}
+
+// localDateSQL 记录时间在 loc 时区的日期，配合 loc.String() 作为参数使用。
+// 要求 timestamp 列为 timestamptz，旧库的 timestamp 列在启动迁移时转换
+const localDateSQL = "DATE(timestamp AT TIME ZONE ?)"
+
+// userTimezoneSQL 关联 users 表时每个用户的时区，配合 utils.DefaultTimezone 作为参数使用
+const userTimezoneSQL = "COALESCE(NULLIF(users.timezone, ''), ?)"
+
+// DetectTimezone 用户尚未设置时区时记录客户端上报的时区，已设置的时区不会被覆盖
+func DetectTimezone(db *gorm.DB, userID uuid.UUID, timezone string) error {
+	if !utils.IsValidTimezone(timezone) {
+		return nil
+	}
+	return db.Model(&models.User{}).
+		Where("id = ? AND timezone = ''", userID).
+		Update("timezone", timezone).Error
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/auth"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，该登录会话已失效，请重新登录")
+	ErrSessionRevoked      = errors.New("登录会话已失效")
+	ErrUserSuspended       = errors.New("账号已被停用")
+)
+
+// DeviceInfo 发起请求的客户端信息（用于登录会话和审计事件）
+type DeviceInfo struct {
+	Name      string
+	UserAgent string
+	IP        string
+}
+
+// Label 设备标签，未指定设备名时使用 User-Agent
+func (d DeviceInfo) Label() string {
+	label := d.Name
+	if label == "" {
+		label = d.UserAgent
+	}
+	if len(label) > 100 {
+		label = label[:100]
+	}
+	return label
+}
+
+// RefreshTokenReusedError 刷新令牌被重放，携带被吊销的会话（errors.Is 匹配 ErrRefreshTokenReused）
+type RefreshTokenReusedError struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+}
+
+func (e *RefreshTokenReusedError) Error() string { return ErrRefreshTokenReused.Error() }
+func (e *RefreshTokenReusedError) Unwrap() error { return ErrRefreshTokenReused }
+
+// TokenPair 登录/刷新后下发的令牌
+type TokenPair struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresIn    int64     `json:"expires_in"` // 访问令牌有效期（秒）
+	SessionID    uuid.UUID `json:"session_id"`
+	UserID       uuid.UUID `json:"-"`
+}
+
+type TokenService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewTokenService(db *gorm.DB, cfg *config.Config) *TokenService {
+	return &TokenService{db: db, cfg: cfg}
+}
+
+// IssueTokens 为一次新的登录创建会话并签发访问令牌和刷新令牌
+func (s *TokenService) IssueTokens(userID uuid.UUID, device DeviceInfo) (*TokenPair, error) {
+	userAgent := device.UserAgent
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+
+	var pair *TokenPair
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		session := models.UserSession{
+			UserID:     userID,
+			DeviceName: device.Label(),
+			UserAgent:  userAgent,
+			IP:         device.IP,
+			ExpiresAt:  now.Add(s.cfg.RefreshTokenExpiration),
+			LastSeenAt: now,
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		pair, err = s.issue(tx, userID, session.ID, session.DeviceName, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *TokenService) issue(tx *gorm.DB, userID, sessionID uuid.UUID, deviceName string, rotatedFrom *uuid.UUID) (*TokenPair, error) {
+	var user models.User
+	if err := tx.Select("id", "role", "suspended_at", "suspended_until").First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	if user.IsSuspended(time.Now()) {
+		return nil, ErrUserSuspended
+	}
+
+	refreshToken, err := auth.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.RefreshToken{
+		UserID:      userID,
+		SessionID:   sessionID,
+		TokenHash:   auth.HashToken(refreshToken),
+		DeviceName:  deviceName,
+		RotatedFrom: rotatedFrom,
+		ExpiresAt:   time.Now().Add(s.cfg.RefreshTokenExpiration),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.UserSession{}).Where("id = ?", sessionID).Update("expires_at", record.ExpiresAt).Error; err != nil {
+		return nil, err
+	}
+
+	accessToken, err := auth.GenerateToken(userID, sessionID, user.Role, s.cfg.JWTKeySet, s.cfg.JWTExpiration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.cfg.JWTExpiration.Seconds()),
+		SessionID:    sessionID,
+		UserID:       userID,
+	}, nil
+}
+
+// Refresh 轮换刷新令牌：旧令牌作废并签发新令牌。
+// 已作废的令牌被再次使用时视为泄露，整个令牌族都会被吊销
+func (s *TokenService) Refresh(refreshToken string) (*TokenPair, error) {
+	var pair *TokenPair
+	var reused *RefreshTokenReusedError
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Where("token_hash = ?", auth.HashToken(refreshToken)).First(&current).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+		if current.RevokedAt != nil {
+			utils.APILog("[TokenService.Refresh] ⚠️ 检测到刷新令牌重放，吊销会话 %s（用户 %s）", current.SessionID, current.UserID)
+			reused = &RefreshTokenReusedError{UserID: current.UserID, SessionID: current.SessionID}
+			return revokeSessions(tx, now, "id = ?", current.SessionID)
+		}
+		if now.After(current.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		// 条件更新，防止并发刷新时同一令牌被轮换两次
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenInvalid
+		}
+
+		var err error
+		pair, err = s.issue(tx, current.UserID, current.SessionID, current.DeviceName, &current.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused != nil {
+		return nil, reused
+	}
+	return pair, nil
+}
+
+// RevokeByRefreshToken 根据刷新令牌吊销其所在会话（用于退出登录），返回被吊销的会话
+func (s *TokenService) RevokeByRefreshToken(refreshToken string) (*models.UserSession, error) {
+	var record models.RefreshToken
+	if err := s.db.Where("token_hash = ?", auth.HashToken(refreshToken)).First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	var session models.UserSession
+	if err := s.db.First(&session, "id = ?", record.SessionID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.RevokeSession(record.UserID, record.SessionID); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RevokeSession 吊销用户的某个登录会话
+func (s *TokenService) RevokeSession(userID, sessionID uuid.UUID) error {
+	return revokeSessions(s.db, time.Now(), "id = ? AND user_id = ?", sessionID, userID)
+}
+
+// RevokeAllSessions 吊销用户的全部登录会话
+func (s *TokenService) RevokeAllSessions(userID uuid.UUID) error {
+	return revokeSessions(s.db, time.Now(), "user_id = ?", userID)
+}
+
+// RevokeOtherSessions 吊销用户除当前会话外的全部登录会话
+func (s *TokenService) RevokeOtherSessions(userID, currentSessionID uuid.UUID) error {
+	return revokeSessions(s.db, time.Now(), "user_id = ? AND id != ?", userID, currentSessionID)
+}
+
+// revokeSessions 吊销条件选中的会话及其下所有刷新令牌
+func revokeSessions(db *gorm.DB, now time.Time, query string, args ...interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var sessionIDs []uuid.UUID
+		if err := tx.Model(&models.UserSession{}).
+			Where(query, args...).
+			Where("revoked_at IS NULL").
+			Pluck("id", &sessionIDs).Error; err != nil {
+			return err
+		}
+		if len(sessionIDs) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.UserSession{}).
+			Where("id IN ?", sessionIDs).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("session_id IN ? AND revoked_at IS NULL", sessionIDs).
+			Update("revoked_at", now).Error
+	})
+}
+
+// ValidateAccessToken 校验访问令牌签名和有效期，确认所属会话未被吊销、账号未被封禁，
+// 并以数据库中的角色为准（角色变更立即生效），同时带出用户已设置的时区
+func (s *TokenService) ValidateAccessToken(tokenString string) (*auth.Claims, error) {
+	claims, err := auth.ValidateToken(tokenString, s.cfg.JWTKeySet)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" || claims.SessionID == uuid.Nil {
+		return nil, ErrSessionRevoked
+	}
+
+	var session models.UserSession
+	if err := s.db.Select("id", "user_id", "expires_at", "revoked_at").
+		First(&session, "id = ?", claims.SessionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSessionRevoked
+		}
+		return nil, err
+	}
+	if session.UserID != claims.UserID || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionRevoked
+	}
+
+	var user models.User
+	if err := s.db.Select("id", "role", "timezone", "suspended_at", "suspended_until").
+		First(&user, "id = ?", claims.UserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSessionRevoked
+		}
+		return nil, err
+	}
+	if user.IsSuspended(time.Now()) {
+		return nil, ErrUserSuspended
+	}
+	claims.Role = user.Role
+	claims.Timezone = user.Timezone
+
+	return claims, nil
+}
@@ -3,21 +3,27 @@ package services
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
 	"time"
 
 	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/meditation"
 	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type TrainingService struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db                *gorm.DB
+	cfg               *config.Config
+	assignmentService *AssignmentService
+	streakService     *StreakService
+	goalService       *GoalService
 }
 
 func NewTrainingService(db *gorm.DB, cfg *config.Config) *TrainingService {
-	return &TrainingService{db: db, cfg: cfg}
+	return &TrainingService{db: db, cfg: cfg, assignmentService: NewAssignmentService(db), streakService: NewStreakService(db), goalService: NewGoalService(db)}
 }
 
 func (s *TrainingService) CreateRecord(userID uuid.UUID, recordType string, duration int, data models.JSONB, timestamp time.Time) (*models.TrainingRecord, error) {
@@ -35,92 +41,113 @@ func (s *TrainingService) CreateRecord(userID uuid.UUID, recordType string, dura
 
 	// 如果是冥想记录，更新冥想进度
 	if recordType == "meditation" {
-		s.updateMeditationProgress(userID, data, duration)
+		s.updateMeditationProgress(&record)
 	}
 
 	// 检查并解锁成就
 	s.checkAndUnlockAchievements(userID, recordType)
+	s.streakService.CheckMilestones(userID)
+
+	// 计入治疗师布置的作业进度
+	s.assignmentService.RecordProgress(&record)
+
+	// 计入用户自己设定的练习目标
+	s.goalService.RecordProgress(&record)
 
 	return &record, nil
 }
 
-func (s *TrainingService) updateMeditationProgress(userID uuid.UUID, data models.JSONB, duration int) {
-	stage, ok := data["stage"].(float64)
+// stages 当前生效的冥想阶段定义
+func (s *TrainingService) stages() *meditation.StageSet {
+	if s.cfg != nil && s.cfg.MeditationStages != nil {
+		return s.cfg.MeditationStages
+	}
+	return meditation.Default()
+}
+
+// loadMeditationProgress 读取用户各阶段的进度
+func (s *TrainingService) loadMeditationProgress(userID uuid.UUID) (map[int]models.MeditationProgress, error) {
+	var rows []models.MeditationProgress
+	if err := s.db.Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	progress := make(map[int]models.MeditationProgress, len(rows))
+	for _, p := range rows {
+		progress[p.Stage] = p
+	}
+	return progress, nil
+}
+
+// stageProgress 转换为阶段引擎使用的进度，没有前置阶段的阶段视为已解锁
+func stageProgress(set *meditation.StageSet, rows map[int]models.MeditationProgress) map[int]meditation.Progress {
+	progress := make(map[int]meditation.Progress, len(set.Stages))
+	for _, stage := range set.Stages {
+		row := rows[stage.ID]
+		progress[stage.ID] = meditation.Progress{
+			CompletedDays: row.CompletedDays,
+			Unlocked:      row.Unlocked || len(stage.Prerequisites) == 0,
+		}
+	}
+	return progress
+}
+
+// updateMeditationProgress 根据阶段定义更新冥想进度：已解锁阶段当天首次达到目标时长计一个有效天数，
+// 然后解锁前置条件已满足的阶段
+func (s *TrainingService) updateMeditationProgress(record *models.TrainingRecord) {
+	stageValue, ok := record.Data["stage"].(float64)
 	if !ok {
 		return
 	}
-	stageInt := int(stage)
+	stageID := int(stageValue)
 
-	// 获取目标时长（秒）
-	targetDurations := map[int]int{1: 300, 2: 720, 3: 30}
-	targetDuration := targetDurations[stageInt]
-	if duration < targetDuration {
-		return // 未达到100%目标时长，不计入有效天数
+	set := s.stages()
+	stage, ok := set.Stage(stageID)
+	if !ok || record.Duration < stage.TargetDuration {
+		return // 未知阶段或未达到目标时长，不计入有效天数
 	}
 
-	// 检查今天是否已有该阶段的记录
-	var todayRecord models.TrainingRecord
-	today := time.Now().Format("2006-01-02")
-	s.db.Where("user_id = ? AND type = 'meditation' AND DATE(timestamp) = ?", userID, today).
-		Where("data->>'stage' = ?", stageInt).
-		First(&todayRecord)
+	rows, err := s.loadMeditationProgress(record.UserID)
+	if err != nil {
+		utils.APILog("[TrainingService.updateMeditationProgress] ❌ 读取用户 %s 的冥想进度失败: %v", record.UserID, err)
+		return
+	}
+	if !stageProgress(set, rows)[stageID].Unlocked {
+		return // 阶段尚未解锁
+	}
 
-	if todayRecord.ID != uuid.Nil {
-		return // 今天已有记录，不重复计算
+	// 用户当地日期的当天已有该阶段的达标记录则不重复计算
+	dayStart := utils.StartOfDay(record.Timestamp, userLocation(s.db, record.UserID))
+	var count int64
+	s.db.Model(&models.TrainingRecord{}).
+		Where("user_id = ? AND type = 'meditation' AND id <> ? AND timestamp >= ? AND timestamp < ?", record.UserID, record.ID, dayStart, dayStart.AddDate(0, 0, 1)).
+		Where("data->>'stage' = ? AND duration >= ?", strconv.Itoa(stageID), stage.TargetDuration).
+		Count(&count)
+	if count > 0 {
+		return
 	}
 
-	// 更新或创建进度记录
-	var progress models.MeditationProgress
-	err := s.db.Where("user_id = ? AND stage = ?", userID, stageInt).First(&progress).Error
-	if err == gorm.ErrRecordNotFound {
-		progress = models.MeditationProgress{
-			UserID:        userID,
-			Stage:         stageInt,
-			CompletedDays: 1,
-			Unlocked:      stageInt == 1, // 阶段1默认解锁
-		}
-		s.db.Create(&progress)
-	} else {
-		progress.CompletedDays++
-		s.db.Save(&progress)
-	}
-
-	// 检查解锁条件
-	if stageInt == 1 && progress.CompletedDays >= 14 {
-		// 解锁阶段2
-		var stage2Progress models.MeditationProgress
-		if err := s.db.Where("user_id = ? AND stage = ?", userID, 2).First(&stage2Progress).Error; err == gorm.ErrRecordNotFound {
-			stage2Progress = models.MeditationProgress{
-				UserID:        userID,
-				Stage:         2,
-				CompletedDays: 0,
-				Unlocked:      true,
-			}
-			s.db.Create(&stage2Progress)
-		} else {
-			stage2Progress.Unlocked = true
-			s.db.Save(&stage2Progress)
-		}
+	progress, exists := rows[stageID]
+	if !exists {
+		progress = models.MeditationProgress{UserID: record.UserID, Stage: stageID, Unlocked: true}
 	}
+	progress.CompletedDays++
+	progress.TotalTime += record.Duration
+	progress.Unlocked = true
+	if err := s.db.Save(&progress).Error; err != nil {
+		utils.APILog("[TrainingService.updateMeditationProgress] ❌ 保存用户 %s 阶段 %d 的进度失败: %v", record.UserID, stageID, err)
+		return
+	}
+	rows[stageID] = progress
 
-	if stageInt == 2 && progress.CompletedDays >= 14 {
-		// 检查阶段1是否也完成14天
-		var stage1Progress models.MeditationProgress
-		if err := s.db.Where("user_id = ? AND stage = ?", userID, 1).First(&stage1Progress).Error; err == nil && stage1Progress.CompletedDays >= 14 {
-			// 解锁阶段3
-			var stage3Progress models.MeditationProgress
-			if err := s.db.Where("user_id = ? AND stage = ?", userID, 3).First(&stage3Progress).Error; err == gorm.ErrRecordNotFound {
-				stage3Progress = models.MeditationProgress{
-					UserID:        userID,
-					Stage:         3,
-					CompletedDays: 0,
-					Unlocked:      true,
-				}
-				s.db.Create(&stage3Progress)
-			} else {
-				stage3Progress.Unlocked = true
-				s.db.Save(&stage3Progress)
-			}
+	// 解锁前置条件已满足的阶段
+	for _, unlockID := range set.NewlyUnlocked(stageProgress(set, rows)) {
+		unlocked, exists := rows[unlockID]
+		if !exists {
+			unlocked = models.MeditationProgress{UserID: record.UserID, Stage: unlockID}
+		}
+		unlocked.Unlocked = true
+		if err := s.db.Save(&unlocked).Error; err != nil {
+			utils.APILog("[TrainingService.updateMeditationProgress] ❌ 解锁用户 %s 阶段 %d 失败: %v", record.UserID, unlockID, err)
 		}
 	}
 }
@@ -178,11 +205,7 @@ func (s *TrainingService) GetStats(userID uuid.UUID) (map[string]interface{}, er
 		Select("COALESCE(SUM(duration), 0)").
 		Scan(&totalDuration)
 
-	var totalDays int
-	s.db.Model(&models.TrainingRecord{}).
-		Where("user_id = ?", userID).
-		Select("COUNT(DISTINCT DATE(timestamp))").
-		Scan(&totalDays)
+	totalDays, _ := s.GetTotalTrainingDays(userID)
 
 	return map[string]interface{}{
 		"total_minutes": totalDuration / 60,
@@ -190,12 +213,12 @@ func (s *TrainingService) GetStats(userID uuid.UUID) (map[string]interface{}, er
 	}, nil
 }
 
-// GetTotalTrainingDays 获取用户总锻炼天数
+// GetTotalTrainingDays 获取用户总锻炼天数（按用户当地日期去重）
 func (s *TrainingService) GetTotalTrainingDays(userID uuid.UUID) (int, error) {
 	var totalDays int
 	err := s.db.Model(&models.TrainingRecord{}).
 		Where("user_id = ?", userID).
-		Select("COUNT(DISTINCT DATE(timestamp))").
+		Select("COUNT(DISTINCT "+localDateSQL+")", userLocation(s.db, userID).String()).
 		Scan(&totalDays).Error
 	return totalDays, err
 }
@@ -230,66 +253,72 @@ func (s *TrainingService) GetTotalTrainingMinutes(userID uuid.UUID) (int, error)
 	return totalDurationSeconds / 60, err
 }
 
+// MeditationStageStatus 阶段定义及用户在该阶段的进度
+type MeditationStageStatus struct {
+	meditation.Stage
+	Unlocked      bool                     `json:"unlocked"`
+	Completed     bool                     `json:"completed"`
+	CompletedDays int                      `json:"completed_days"`
+	TotalTime     int                      `json:"total_time"`
+	Requirements  []meditation.Requirement `json:"requirements"` // 解锁还需完成的前置阶段，已解锁时为空
+}
+
+// GetMeditationProgress 返回全部阶段及用户进度，未解锁阶段附带解锁所需的剩余条件
 func (s *TrainingService) GetMeditationProgress(userID uuid.UUID) (map[string]interface{}, error) {
-	var progress []models.MeditationProgress
-	if err := s.db.Where("user_id = ?", userID).Order("stage ASC").Find(&progress).Error; err != nil {
+	rows, err := s.loadMeditationProgress(userID)
+	if err != nil {
 		return nil, err
 	}
 
-	// 如果没有任何进度记录，创建阶段1的默认记录
-	if len(progress) == 0 {
-		progress = []models.MeditationProgress{
-			{
-				UserID:        userID,
-				Stage:         1,
-				CompletedDays: 0,
-				Unlocked:      true,
-			},
-		}
-	}
+	set := s.stages()
+	progress := stageProgress(set, rows)
 
-	// 构建返回数据结构
 	unlockedStages := []int{}
 	progressDays := make(map[int]int)
-
-	for _, p := range progress {
-		if p.Unlocked {
-			unlockedStages = append(unlockedStages, p.Stage)
+	stages := make([]MeditationStageStatus, 0, len(set.Stages))
+	for _, stage := range set.Stages {
+		p := progress[stage.ID]
+		status := MeditationStageStatus{
+			Stage:         stage,
+			Unlocked:      p.Unlocked,
+			Completed:     set.IsCompleted(stage.ID, progress),
+			CompletedDays: p.CompletedDays,
+			TotalTime:     rows[stage.ID].TotalTime,
+			Requirements:  []meditation.Requirement{},
 		}
-		progressDays[p.Stage] = p.CompletedDays
-	}
-
-	// 确保至少有阶段1解锁
-	stage1Found := false
-	for _, stage := range unlockedStages {
-		if stage == 1 {
-			stage1Found = true
-			break
-		}
-	}
-	if !stage1Found {
-		unlockedStages = append([]int{1}, unlockedStages...)
-		if progressDays[1] == 0 {
-			progressDays[1] = 0
+		if p.Unlocked {
+			unlockedStages = append(unlockedStages, stage.ID)
+		} else if missing := set.Requirements(stage.ID, progress); missing != nil {
+			status.Requirements = missing
 		}
+		progressDays[stage.ID] = p.CompletedDays
+		stages = append(stages, status)
 	}
 
 	return map[string]interface{}{
+		"version":         set.Version,
 		"unlocked_stages": unlockedStages,
 		"progress_days":   progressDays,
+		"stages":          stages,
 	}, nil
 }
 
-// GetWeeklyStats 获取用户过去7天的训练统计
+// GetStreak 获取用户的连续练习天数和冻结次数
+func (s *TrainingService) GetStreak(userID uuid.UUID) (*Streak, error) {
+	return s.streakService.Get(userID)
+}
+
+// GetWeeklyStats 获取用户过去7天（按用户当地日期）的训练统计
 func (s *TrainingService) GetWeeklyStats(userID uuid.UUID) ([]map[string]interface{}, error) {
 	var weeklyStats []map[string]interface{}
+	today := utils.StartOfDay(time.Now(), userLocation(s.db, userID))
 	
 	// 获取过去7天的日期
 	for i := 6; i >= 0; i-- {
-		date := time.Now().AddDate(0, 0, -i)
+		date := today.AddDate(0, 0, -i)
 		var count int64
 		s.db.Model(&models.TrainingRecord{}).
-			Where("user_id = ? AND DATE(timestamp) = DATE(?)", userID, date).
+			Where("user_id = ? AND timestamp >= ? AND timestamp < ?", userID, date, date.AddDate(0, 0, 1)).
 			Count(&count)
 		
 		weeklyStats = append(weeklyStats, map[string]interface{}{
@@ -495,16 +524,17 @@ type ProgressTrendData struct {
 	Value int    `json:"value"`
 }
 
-// GetProgressTrend 获取用户的进步趋势
+// GetProgressTrend 获取用户的进步趋势（按用户当地日期）
 func (s *TrainingService) GetProgressTrend(userID uuid.UUID) ([]ProgressTrendData, error) {
 	var trendData []ProgressTrendData
+	today := utils.StartOfDay(time.Now(), userLocation(s.db, userID))
 	
 	// 获取过去30天的趋势数据
 	for i := 29; i >= 0; i-- {
-		date := time.Now().AddDate(0, 0, -i)
+		date := today.AddDate(0, 0, -i)
 		var totalDuration int
 		s.db.Model(&models.TrainingRecord{}).
-			Where("user_id = ? AND DATE(timestamp) = DATE(?)", userID, date).
+			Where("user_id = ? AND timestamp >= ? AND timestamp < ?", userID, date, date.AddDate(0, 0, 1)).
 			Select("COALESCE(SUM(duration), 0)").
 			Scan(&totalDuration)
 		
@@ -534,11 +564,13 @@ func (s *TrainingService) GetLearningPartnerStats(userID uuid.UUID) (LearningPar
 	}
 
 	var todayActive int64
-	// 今日活跃用户：今天有训练记录的独立用户
-	today := time.Now().Format("2006-01-02")
+	// 今日活跃用户：在各自当地日期的今天有训练记录的独立用户（任何时区的今天都开始于24小时内）
 	if err := s.db.Model(&models.TrainingRecord{}).
-		Where("DATE(timestamp) = ?", today).
-		Distinct("user_id").
+		Joins("JOIN users ON users.id = training_records.user_id").
+		Where("training_records.timestamp > ?", time.Now().Add(-24*time.Hour)).
+		Where("DATE(training_records.timestamp AT TIME ZONE "+userTimezoneSQL+") = DATE(NOW() AT TIME ZONE "+userTimezoneSQL+")",
+			utils.DefaultTimezone, utils.DefaultTimezone).
+		Distinct("training_records.user_id").
 		Count(&todayActive).Error; err != nil {
 		return LearningPartnerStats{}, err
 	}
@@ -559,14 +591,32 @@ type LearningPartner struct {
 	Progress int    `json:"progress"` // 0-100
 }
 
-// GetLearningPartners 获取学习伙伴列表
+// GetLearningPartners 获取学习伙伴列表。设置了不出现在学习伙伴中的用户会被排除，
+// 隐藏训练统计的用户不展示训练动态
 func (s *TrainingService) GetLearningPartners(userID uuid.UUID) ([]LearningPartner, error) {
 	var users []models.User
 	// Fetch a random set of users, excluding the current user
-	if err := s.db.Where("id != ?", userID).Order("RANDOM()").Limit(5).Find(&users).Error; err != nil {
+	if err := s.db.Where("id != ?", userID).Where(notHiddenFromPartnersSQL).Order("RANDOM()").Limit(5).Find(&users).Error; err != nil {
 		return nil, err
 	}
 
+	var userIDs []uuid.UUID
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+	statsHidden := make(map[uuid.UUID]bool)
+	if len(userIDs) > 0 {
+		var hiddenIDs []uuid.UUID
+		if err := s.db.Model(&models.PrivacySettings{}).
+			Where("user_id IN ? AND hide_training_stats", userIDs).
+			Pluck("user_id", &hiddenIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range hiddenIDs {
+			statsHidden[id] = true
+		}
+	}
+
 	var partners []LearningPartner
 	for _, user := range users {
 		fmt.Printf("Processing learning partner: UserID=%s, Username=%s\n", user.ID, user.Username)
@@ -576,7 +626,9 @@ func (s *TrainingService) GetLearningPartners(userID uuid.UUID) ([]LearningPartn
 
 		var latestRecord models.TrainingRecord
 		// Find the most recent training record for the user
-		s.db.Where("user_id = ?", user.ID).Order("timestamp DESC").First(&latestRecord)
+		if !statsHidden[user.ID] {
+			s.db.Where("user_id = ?", user.ID).Order("timestamp DESC").First(&latestRecord)
+		}
 
 		if latestRecord.ID != uuid.Nil { // If a training record exists
 			fmt.Printf("  Found latest record for %s: Type=%s, Timestamp=%s\n", user.Username, latestRecord.Type, latestRecord.Timestamp)
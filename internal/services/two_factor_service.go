@@ -0,0 +1,424 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"fluent-life-backend/internal/config"
+	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/auth"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// 允许前后各一个时间步的时钟偏差
+	totpSkew = 1
+)
+
+var (
+	ErrTwoFactorNotEnabled     = errors.New("未开启两步验证")
+	ErrTwoFactorAlreadyEnabled = errors.New("已开启两步验证")
+	ErrTwoFactorNotEnrolling   = errors.New("请先获取两步验证密钥")
+	ErrTwoFactorCodeInvalid    = errors.New("验证码错误")
+	ErrTwoFactorChallenge      = errors.New("两步验证已过期，请重新登录")
+)
+
+// TwoFactorChallenge 开启两步验证的账号在密码（或验证码、第三方）登录后得到的挑战，
+// 客户端需要携带 ChallengeToken 和动态验证码完成登录
+type TwoFactorChallenge struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int64  `json:"expires_in"` // 秒
+}
+
+// TwoFactorEnrollment 开始绑定验证器时返回的密钥
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorStatus 两步验证状态
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorService TOTP 两步验证
+type TwoFactorService struct {
+	db            *gorm.DB
+	cfg           *config.Config
+	loginThrottle *LoginThrottleService
+}
+
+func NewTwoFactorService(db *gorm.DB, cfg *config.Config) *TwoFactorService {
+	return &TwoFactorService{
+		db:            db,
+		cfg:           cfg,
+		loginThrottle: NewLoginThrottleService(db, cfg),
+	}
+}
+
+// IsEnabled 用户是否已开启两步验证
+func (s *TwoFactorService) IsEnabled(userID uuid.UUID) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.TwoFactor{}).Where("user_id = ? AND enabled = ?", userID, true).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Status 获取两步验证状态
+func (s *TwoFactorService) Status(userID uuid.UUID) (*TwoFactorStatus, error) {
+	status := &TwoFactorStatus{}
+
+	var tf models.TwoFactor
+	err := s.db.Where("user_id = ? AND enabled = ?", userID, true).First(&tf).Error
+	if err == gorm.ErrRecordNotFound {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status.Enabled = true
+	status.EnabledAt = tf.EnabledAt
+	if err := s.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&status.RecoveryCodesRemaining).Error; err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// BeginEnrollment 生成新的 TOTP 密钥（未确认前不生效），重复调用会替换未确认的密钥
+func (s *TwoFactorService) BeginEnrollment(userID uuid.UUID) (*TwoFactorEnrollment, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	var tf models.TwoFactor
+	err := s.db.Where("user_id = ?", userID).First(&tf).Error
+	if err == nil && tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := auth.EncryptSecret(s.cfg.TwoFactor.EncryptionKey, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if tf.ID == uuid.Nil {
+		tf = models.TwoFactor{UserID: userID, SecretEncrypted: encrypted}
+		if err := s.db.Create(&tf).Error; err != nil {
+			return nil, err
+		}
+	} else if err := s.db.Model(&tf).Updates(map[string]interface{}{
+		"secret_encrypted": encrypted,
+		"last_used_step":   0,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(s.cfg.TwoFactor.Issuer, accountLabel(&user), secret),
+	}, nil
+}
+
+// ConfirmEnrollment 用验证器上的动态码确认绑定，开启两步验证并返回一次性恢复码（只展示这一次）
+func (s *TwoFactorService) ConfirmEnrollment(userID uuid.UUID, code string) ([]string, error) {
+	var tf models.TwoFactor
+	if err := s.db.Where("user_id = ?", userID).First(&tf).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTwoFactorNotEnrolling
+		}
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	if err := s.verifyTOTP(&tf, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&tf).Updates(map[string]interface{}{
+			"enabled":    true,
+			"enabled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	utils.APILog("[TwoFactorService.ConfirmEnrollment] 用户 %s 开启了两步验证", userID)
+	return codes, nil
+}
+
+// Disable 关闭两步验证，需要提供当前的动态验证码
+func (s *TwoFactorService) Disable(userID uuid.UUID, code, ip string) error {
+	tf, err := s.enabled(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyTOTPThrottled(userID, tf, code, ip); err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tf).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	utils.APILog("[TwoFactorService.Disable] 用户 %s 关闭了两步验证", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（旧的全部作废），需要提供当前的动态验证码
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uuid.UUID, code, ip string) ([]string, error) {
+	tf, err := s.enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyTOTPThrottled(userID, tf, code, ip); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	utils.APILog("[TwoFactorService.RegenerateRecoveryCodes] 用户 %s 重新生成了恢复码", userID)
+	return codes, nil
+}
+
+// IssueChallenge 为已通过第一步认证的用户签发两步验证挑战令牌
+func (s *TwoFactorService) IssueChallenge(userID uuid.UUID) (*TwoFactorChallenge, error) {
+	expiration := s.cfg.TwoFactor.ChallengeExpiration
+	token, err := auth.GenerateChallengeToken(userID, auth.PurposeTwoFactorChallenge, s.cfg.JWTKeySet, expiration)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{ChallengeToken: token, ExpiresIn: int64(expiration.Seconds())}, nil
+}
+
+// VerifyChallenge 校验挑战令牌和动态验证码（或恢复码），返回通过验证的用户 ID。
+// 挑战令牌有效但验证码错误时同样返回用户 ID（用于审计）。失败次数计入登录退避
+func (s *TwoFactorService) VerifyChallenge(challengeToken, code, ip string) (uuid.UUID, error) {
+	claims, err := auth.ValidateToken(challengeToken, s.cfg.JWTKeySet)
+	if err != nil || claims.Purpose != auth.PurposeTwoFactorChallenge {
+		return uuid.Nil, ErrTwoFactorChallenge
+	}
+	userID := claims.UserID
+
+	throttleKey := "2fa:" + userID.String()
+	if err := s.loginThrottle.Check(throttleKey, ip); err != nil {
+		return userID, err
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return userID, ErrTwoFactorChallenge
+	}
+	if err := s.loginThrottle.CheckUserLocked(&user); err != nil {
+		return userID, err
+	}
+
+	tf, err := s.enabled(userID)
+	if err != nil {
+		return userID, err
+	}
+
+	if err := s.verifyTOTP(tf, code); err != nil {
+		if !errors.Is(err, ErrTwoFactorCodeInvalid) {
+			return userID, err
+		}
+		used, err := s.useRecoveryCode(userID, code)
+		if err != nil {
+			return userID, err
+		}
+		if !used {
+			s.loginThrottle.RecordFailure(throttleKey, ip, &user)
+			if err := s.loginThrottle.CheckUserLocked(&user); err != nil {
+				return userID, err
+			}
+			return userID, ErrTwoFactorCodeInvalid
+		}
+		utils.APILog("[TwoFactorService.VerifyChallenge] 用户 %s 使用恢复码完成了两步验证", userID)
+	}
+	s.loginThrottle.Reset(throttleKey)
+
+	return userID, nil
+}
+
+// verifyTOTPThrottled 校验已登录用户提交的动态验证码，失败次数与 VerifyChallenge 共用退避和账号锁定，
+// 防止持有会话的人暴力猜测验证码来关闭两步验证
+func (s *TwoFactorService) verifyTOTPThrottled(userID uuid.UUID, tf *models.TwoFactor, code, ip string) error {
+	throttleKey := "2fa:" + userID.String()
+	if err := s.loginThrottle.Check(throttleKey, ip); err != nil {
+		return err
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	if err := s.loginThrottle.CheckUserLocked(&user); err != nil {
+		return err
+	}
+
+	if err := s.verifyTOTP(tf, code); err != nil {
+		if errors.Is(err, ErrTwoFactorCodeInvalid) {
+			s.loginThrottle.RecordFailure(throttleKey, ip, &user)
+			if err := s.loginThrottle.CheckUserLocked(&user); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	s.loginThrottle.Reset(throttleKey)
+	return nil
+}
+
+func (s *TwoFactorService) enabled(userID uuid.UUID) (*models.TwoFactor, error) {
+	var tf models.TwoFactor
+	if err := s.db.Where("user_id = ? AND enabled = ?", userID, true).First(&tf).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTwoFactorNotEnabled
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// verifyTOTP 校验动态验证码；同一时间步的验证码只能使用一次
+func (s *TwoFactorService) verifyTOTP(tf *models.TwoFactor, code string) error {
+	secret, err := auth.DecryptSecret(s.cfg.TwoFactor.EncryptionKey, tf.SecretEncrypted)
+	if err != nil {
+		utils.APILog("[TwoFactorService.verifyTOTP] ❌ 用户 %s 的 TOTP 密钥解密失败: %v", tf.UserID, err)
+		return errors.New("两步验证配置异常，请使用恢复码")
+	}
+
+	step, ok := auth.ValidateTOTP(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	result := s.db.Model(&models.TwoFactor{}).
+		Where("id = ? AND last_used_step < ?", tf.ID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+	tf.LastUsedStep = step
+	return nil
+}
+
+// useRecoveryCode 尝试消费一个恢复码
+func (s *TwoFactorService) useRecoveryCode(userID uuid.UUID, code string) (bool, error) {
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return false, nil
+	}
+
+	result := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, recoveryCodeHash(userID, normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成一组新的，返回明文
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: recoveryCodeHash(userID, code)})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// 去掉容易混淆的字符
+const recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = recoveryCodeAlphabet[int(b[i])%len(recoveryCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func recoveryCodeHash(userID uuid.UUID, normalized string) string {
+	return auth.HashToken(userID.String() + ":" + normalized)
+}
+
+// accountLabel 验证器 App 中显示的账号名
+func accountLabel(user *models.User) string {
+	if user.Email != nil {
+		return *user.Email
+	}
+	if user.Phone != nil {
+		return *user.Phone
+	}
+	return user.Username
+}
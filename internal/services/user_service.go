@@ -5,6 +5,7 @@ import (
 
 	"fluent-life-backend/internal/config"
 	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/utils"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -13,6 +14,7 @@ type UserService struct {
 	db                 *gorm.DB
 	trainingService    *TrainingService
 	achievementService *AchievementService
+	privacyService     *PrivacyService
 }
 
 // NewUserService 创建一个新的 UserService 实例
@@ -21,6 +23,7 @@ func NewUserService(db *gorm.DB, cfg *config.Config) *UserService {
 		db:                 db,
 		trainingService:    NewTrainingService(db, cfg),
 		achievementService: NewAchievementService(db),
+		privacyService:     NewPrivacyService(db),
 	}
 }
 
@@ -42,33 +45,15 @@ func (s *UserService) CalculateStats(userID uuid.UUID) (*UserStats, error) {
 
 	totalMinutes := totalDuration / 60
 
-	// 计算总天数（去重）
-	var totalDays int
-	s.db.Model(&models.TrainingRecord{}).
-		Where("user_id = ?", userID).
-		Select("COUNT(DISTINCT DATE(timestamp))").
-		Scan(&totalDays)
+	// 计算总天数（按用户当地日期去重）
+	totalDays, _ := s.trainingService.GetTotalTrainingDays(userID)
 
 	// 计算等级和进度
 	currentLevel := (totalMinutes / 60) + 1
 	levelProgress := (totalMinutes % 60) * 100 / 60
 
 	// 计算最近7天的每日训练时长
-	weeklyData := make([]int, 7)
-	now := time.Now()
-	for i := 6; i >= 0; i-- {
-		date := now.AddDate(0, 0, -i)
-		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-		endOfDay := startOfDay.Add(24 * time.Hour)
-
-		var dayDuration int
-		s.db.Model(&models.TrainingRecord{}).
-			Where("user_id = ? AND timestamp >= ? AND timestamp < ?", userID, startOfDay, endOfDay).
-			Select("COALESCE(SUM(duration), 0)").
-			Scan(&dayDuration)
-
-		weeklyData[6-i] = dayDuration / 60 // 转换为分钟
-	}
+	weeklyData, _ := s.GetWeeklyActivity(userID)
 
 	return &UserStats{
 		TotalMinutes:  totalMinutes,
@@ -79,14 +64,13 @@ func (s *UserService) CalculateStats(userID uuid.UUID) (*UserStats, error) {
 	}, nil
 }
 
-// GetWeeklyActivity 计算用户本周活跃度（例如，过去7天每天的训练时长）
+// GetWeeklyActivity 计算用户本周活跃度（例如，过去7天每天的训练时长），按用户当地日期划分
 func (s *UserService) GetWeeklyActivity(userID uuid.UUID) ([]int, error) {
 	weeklyData := make([]int, 7)
-	now := time.Now()
+	today := utils.StartOfDay(time.Now(), userLocation(s.db, userID))
 	for i := 6; i >= 0; i-- {
-		date := now.AddDate(0, 0, -i)
-		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-		endOfDay := startOfDay.Add(24 * time.Hour)
+		startOfDay := today.AddDate(0, 0, -i)
+		endOfDay := startOfDay.AddDate(0, 0, 1)
 
 		var dayDuration int
 		s.db.Model(&models.TrainingRecord{}).
@@ -107,7 +91,8 @@ func (s *UserService) GetUserByID(userID uuid.UUID) (*models.User, error) {
 	return &user, nil
 }
 
-// GetUserProfileWithStats 获取用户资料及统计数据
+// GetUserProfileWithStats 获取用户资料及统计数据。查看他人主页时按对方的隐私设置裁剪：
+// 主页不可见时只返回基本信息，隐藏训练统计时不返回训练天数、时长和周活跃度
 func (s *UserService) GetUserProfileWithStats(userID, currentUserID uuid.UUID) (*models.UserProfile, error) {
 	var user models.User
 	if err := s.db.First(&user, userID).Error; err != nil {
@@ -123,6 +108,35 @@ func (s *UserService) GetUserProfileWithStats(userID, currentUserID uuid.UUID) (
 		user.IsFollowing = isFollowing
 	}
 
+	profileVisible, statsVisible, err := s.privacyService.ProfileAccess(currentUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if currentUserID != userID {
+		// 联系方式只对本人可见
+		user.Email = nil
+		user.Phone = nil
+	}
+	if !profileVisible {
+		return &models.UserProfile{
+			User:              user,
+			BraveryBadges:     []models.UserAchievement{},
+			ProfileRestricted: true,
+			StatsHidden:       true,
+		}, nil
+	}
+	if !statsVisible {
+		braveryBadges, err := s.achievementService.GetUserBadges(userID)
+		if err != nil {
+			return nil, err
+		}
+		return &models.UserProfile{
+			User:          user,
+			BraveryBadges: braveryBadges,
+			StatsHidden:   true,
+		}, nil
+	}
+
 	totalTrainingDays, err := s.trainingService.GetTotalTrainingDays(userID)
 	if err != nil {
 		return nil, err
@@ -1,30 +1,121 @@
 package services
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/rand"
+	"math/big"
 	"time"
 
 	"fluent-life-backend/internal/config"
 	"fluent-life-backend/internal/models"
+	"fluent-life-backend/internal/notify"
+	"fluent-life-backend/internal/utils"
+	"fluent-life-backend/pkg/validator"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type VerificationCodeService struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db            *gorm.DB
+	cfg           *config.Config
+	emailNotifier notify.Notifier
+	smsNotifier   notify.Notifier
 }
 
 func NewVerificationCodeService(db *gorm.DB, cfg *config.Config) *VerificationCodeService {
-	return &VerificationCodeService{db: db, cfg: cfg}
+	emailNotifier, err := notify.NewEmailNotifier(cfg)
+	if err != nil {
+		utils.APILog("[VerificationCodeService] ⚠️ 邮件服务配置错误: %v", err)
+	}
+	smsNotifier, err := notify.NewSMSNotifier(cfg)
+	if err != nil {
+		utils.APILog("[VerificationCodeService] ⚠️ 短信服务配置错误: %v", err)
+	}
+
+	return &VerificationCodeService{
+		db:            db,
+		cfg:           cfg,
+		emailNotifier: emailNotifier,
+		smsNotifier:   smsNotifier,
+	}
+}
+
+// codePurposes 验证码用途（用于通知文案）
+var codePurposes = map[string]string{
+	"register":          "注册",
+	"login":             "登录",
+	"reset_password":    "重置密码",
+	"change_identifier": "更换绑定",
 }
 
-func (s *VerificationCodeService) GenerateCode() string {
-	return fmt.Sprintf("%06d", rand.Intn(1000000))
+// expiredCodeRetention 过期验证码的保留时长（发送配额按最近一天的记录统计）
+const expiredCodeRetention = 24 * time.Hour
+
+// GenerateCode 使用 crypto/rand 生成 6 位数字验证码
+func (s *VerificationCodeService) GenerateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashCode 计算验证码摘要，绑定标识和用途，数据库中只保存摘要
+func (s *VerificationCodeService) hashCode(identifier, codeType, code string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.CodeHashKey))
+	mac.Write([]byte(identifier + "|" + codeType + "|" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkSendQuota 检查手机号/邮箱和 IP 的每小时、每天发送配额
+func (s *VerificationCodeService) checkSendQuota(identifier, ip string) error {
+	now := time.Now()
+	limits := s.cfg.CodeLimits
+
+	quotas := []struct {
+		column string
+		value  string
+		since  time.Time
+		limit  int
+		msg    string
+	}{
+		{"identifier", identifier, now.Add(-time.Hour), limits.IdentifierPerHour, "发送过于频繁，请1小时后再试"},
+		{"identifier", identifier, now.Add(-24 * time.Hour), limits.IdentifierPerDay, "今日验证码发送次数已达上限"},
+		{"ip", ip, now.Add(-time.Hour), limits.IPPerHour, "发送过于频繁，请1小时后再试"},
+		{"ip", ip, now.Add(-24 * time.Hour), limits.IPPerDay, "今日验证码发送次数已达上限"},
+	}
+
+	for _, q := range quotas {
+		if q.limit <= 0 || q.value == "" {
+			continue
+		}
+		var count int64
+		if err := s.db.Model(&models.VerificationCode{}).
+			Where(q.column+" = ? AND created_at > ?", q.value, q.since).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("发送验证码失败")
+		}
+		if count >= int64(q.limit) {
+			utils.APILog("[VerificationCodeService.SendCode] ⚠️ 触发发送配额限制 %s=%s（%d/%d）", q.column, q.value, count, q.limit)
+			return errors.New(q.msg)
+		}
+	}
+	return nil
 }
 
-func (s *VerificationCodeService) SendCode(identifier, codeType string) error {
+func (s *VerificationCodeService) SendCode(identifier, codeType, ip string) error {
+	// 重置密码只对已注册账号发送，但不向调用方暴露账号是否存在
+	if codeType == "reset_password" {
+		if _, err := findUserByIdentifier(s.db, identifier); err != nil {
+			return nil
+		}
+	}
+
 	// 检查1分钟内是否已发送
 	var recentCode models.VerificationCode
 	oneMinuteAgo := time.Now().Add(-1 * time.Minute)
@@ -33,15 +124,23 @@ func (s *VerificationCodeService) SendCode(identifier, codeType string) error {
 		return fmt.Errorf("请稍后再试，1分钟内只能发送一次")
 	}
 
+	if err := s.checkSendQuota(identifier, ip); err != nil {
+		return err
+	}
+
 	// 生成验证码
-	code := s.GenerateCode()
+	code, err := s.GenerateCode()
+	if err != nil {
+		return fmt.Errorf("生成验证码失败: %w", err)
+	}
 	expiresAt := time.Now().Add(s.cfg.CodeExpiration)
 
-	// 保存验证码
+	// 保存验证码（只保存摘要）
 	verificationCode := models.VerificationCode{
 		Identifier: identifier,
-		Code:       code,
+		Code:       s.hashCode(identifier, codeType, code),
 		Type:       codeType,
+		IP:         ip,
 		ExpiresAt:  expiresAt,
 		Used:       false,
 	}
@@ -50,24 +149,82 @@ func (s *VerificationCodeService) SendCode(identifier, codeType string) error {
 		return fmt.Errorf("保存验证码失败: %w", err)
 	}
 
-	// 发送验证码（开发环境直接打印，生产环境接入短信/邮件服务）
+	// 发送验证码（开发环境同时打印到控制台）
 	if s.cfg.Environment == "development" {
-		fmt.Printf("[开发环境] 验证码已发送到 %s: %s (有效期5分钟)\n", identifier, code)
-	} else {
-		// TODO: 接入真实的短信/邮件服务
-		// if IsEmail(identifier) {
-		//     sendEmail(identifier, code)
-		// } else {
-		//     sendSMS(identifier, code)
-		// }
+		fmt.Printf("[开发环境] 验证码已发送到 %s: %s (有效期%d分钟)\n", identifier, code, int(s.cfg.CodeExpiration.Minutes()))
+	}
+
+	notifier := s.notifierFor(identifier)
+	if notifier == nil {
+		if s.cfg.Environment != "development" {
+			utils.APILog("[VerificationCodeService.SendCode] ⚠️ 未配置 %s 的发送渠道，验证码未投递", identifier)
+		}
+		s.db.Model(&verificationCode).Update("delivery_status", "skipped")
+		return nil
+	}
+
+	msg := notify.Message{
+		To:      identifier,
+		Subject: "流畅生活验证码",
+		Body: fmt.Sprintf("【流畅生活】您的%s验证码是 %s，%d分钟内有效。如非本人操作请忽略。",
+			codePurposes[codeType], code, int(s.cfg.CodeExpiration.Minutes())),
 	}
+	go s.deliver(verificationCode.ID, notifier, msg)
 
 	return nil
 }
 
+// SendNotice 向邮箱/手机号发送一条通知（非验证码），在后台投递，失败只记录日志
+func (s *VerificationCodeService) SendNotice(identifier, subject, body string) {
+	notifier := s.notifierFor(identifier)
+	if notifier == nil {
+		if s.cfg.Environment == "development" {
+			fmt.Printf("[开发环境] 通知已发送到 %s: %s\n", identifier, body)
+		}
+		return
+	}
+
+	msg := notify.Message{To: identifier, Subject: subject, Body: body}
+	go func() {
+		if attempts, err := notify.SendWithRetry(notifier, msg, s.cfg.Notify.MaxAttempts, 2*time.Second); err != nil {
+			utils.APILog("[VerificationCodeService.SendNotice] ❌ 通知发送到 %s 失败（尝试 %d 次）: %v", identifier, attempts, err)
+		}
+	}()
+}
+
+// notifierFor 根据标识类型选择邮件或短信渠道
+func (s *VerificationCodeService) notifierFor(identifier string) notify.Notifier {
+	if validator.IsEmail(identifier) {
+		return s.emailNotifier
+	}
+	return s.smsNotifier
+}
+
+// deliver 发送验证码（失败重试）并记录投递状态
+func (s *VerificationCodeService) deliver(codeID uuid.UUID, notifier notify.Notifier, msg notify.Message) {
+	attempts, err := notify.SendWithRetry(notifier, msg, s.cfg.Notify.MaxAttempts, 2*time.Second)
+
+	updates := map[string]interface{}{"delivery_attempts": attempts}
+	if err != nil {
+		utils.APILog("[VerificationCodeService.deliver] ❌ 验证码发送到 %s 失败（尝试 %d 次）: %v", msg.To, attempts, err)
+		updates["delivery_status"] = "failed"
+		updates["delivery_error"] = err.Error()
+	} else {
+		updates["delivery_status"] = "sent"
+		updates["delivered_at"] = time.Now()
+	}
+
+	if err := s.db.Model(&models.VerificationCode{}).Where("id = ?", codeID).Updates(updates).Error; err != nil {
+		utils.APILog("[VerificationCodeService.deliver] ⚠️ 更新验证码投递状态失败: %v", err)
+	}
+}
+
+// ValidateCode 校验最近一次发送的验证码。每次校验都会消耗一次尝试机会，
+// 达到 CODE_MAX_ATTEMPTS 后该验证码作废，需要重新获取
 func (s *VerificationCodeService) ValidateCode(identifier, code, codeType string) error {
 	var verificationCode models.VerificationCode
-	err := s.db.Where("identifier = ? AND code = ? AND type = ? AND used = false", identifier, code, codeType).
+	err := s.db.Where("identifier = ? AND type = ? AND used = false", identifier, codeType).
+		Order("created_at DESC").
 		First(&verificationCode).Error
 	if err != nil {
 		return fmt.Errorf("验证码无效")
@@ -77,15 +234,41 @@ func (s *VerificationCodeService) ValidateCode(identifier, code, codeType string
 		return fmt.Errorf("验证码已过期")
 	}
 
+	// 先原子地消耗一次尝试机会，防止并发暴力猜测
+	result := s.db.Model(&models.VerificationCode{}).
+		Where("id = ? AND used = false AND attempts < ?", verificationCode.ID, s.cfg.CodeLimits.MaxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("验证码无效")
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("验证码错误次数过多，请重新获取")
+	}
+
+	expected := s.hashCode(identifier, codeType, code)
+	if !hmac.Equal([]byte(verificationCode.Code), []byte(expected)) {
+		return fmt.Errorf("验证码无效")
+	}
+
 	// 标记为已使用
-	verificationCode.Used = true
-	s.db.Save(&verificationCode)
+	result = s.db.Model(&models.VerificationCode{}).
+		Where("id = ? AND used = false", verificationCode.ID).
+		Update("used", true)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return fmt.Errorf("验证码无效")
+	}
 
 	return nil
 }
 
-
-
-
-
-
+// PurgeExpired 清理过期超过一天的验证码
+func (s *VerificationCodeService) PurgeExpired() error {
+	result := s.db.Where("expires_at < ?", time.Now().Add(-expiredCodeRetention)).Delete(&models.VerificationCode{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		utils.APILog("[VerificationCodeService.PurgeExpired] 已清理 %d 条过期验证码", result.RowsAffected)
+	}
+	return nil
+}
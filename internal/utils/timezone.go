@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultTimezone 用户未设置时区时使用的时区（与数据库连接的时区一致）
+const DefaultTimezone = "Asia/Shanghai"
+
+var locationCache sync.Map // 时区名 -> *time.Location
+
+// IsValidTimezone 判断是否为有效的 IANA 时区名，如 Asia/Shanghai、America/New_York
+func IsValidTimezone(name string) bool {
+	if name == "" || name == "Local" || len(name) > 64 {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// LoadLocation 加载时区，为空或无效时返回默认时区
+func LoadLocation(name string) *time.Location {
+	if !IsValidTimezone(name) {
+		name = DefaultTimezone
+	}
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		// 系统缺少时区数据时退回固定的东八区
+		loc = time.FixedZone(DefaultTimezone, 8*60*60)
+	}
+	locationCache.Store(name, loc)
+	return loc
+}
+
+// StartOfDay t 在 loc 时区当天的零点
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
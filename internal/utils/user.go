@@ -15,8 +15,22 @@ func GetUserID(c *gin.Context) (uuid.UUID, bool) {
 	return id, ok
 }
 
+func GetSessionID(c *gin.Context) (uuid.UUID, bool) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return uuid.Nil, false
+	}
 
+	id, ok := sessionID.(uuid.UUID)
+	return id, ok
+}
 
+func GetRole(c *gin.Context) string {
+	role, exists := c.Get("role")
+	if !exists {
+		return ""
+	}
 
-
-
+	r, _ := role.(string)
+	return r
+}
@@ -44,7 +44,7 @@ CREATE TABLE IF NOT EXISTS training_records (
     type VARCHAR(20) NOT NULL,
     duration INTEGER NOT NULL,
     data JSONB,
-    timestamp TIMESTAMP NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL, -- 按用户当地日期统计依赖带时区的时间
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
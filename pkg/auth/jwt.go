@@ -9,30 +9,48 @@ import (
 )
 
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID uuid.UUID `json:"sid"` // 登录会话（刷新令牌族）ID，用于吊销
+	Role      string    `json:"role"`
+	Purpose   string    `json:"purpose,omitempty"` // 非空表示不是访问令牌（如二次验证挑战令牌）
+	Timezone  string    `json:"-"`                 // 用户已设置的时区，校验令牌时从数据库读取，不写入令牌
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uuid.UUID, secret string, expiration time.Duration) (string, error) {
+func GenerateToken(userID, sessionID uuid.UUID, role string, keys *KeySet, expiration time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
+		Role:      role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.Sign(claims)
 }
 
-func ValidateToken(tokenString, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(secret), nil
-	})
+// PurposeTwoFactorChallenge 二次验证挑战令牌
+const PurposeTwoFactorChallenge = "2fa_challenge"
+
+// GenerateChallengeToken 生成用于后续步骤的短期令牌，不能当作访问令牌使用
+func GenerateChallengeToken(userID uuid.UUID, purpose string, keys *KeySet, expiration time.Duration) (string, error) {
+	claims := Claims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return keys.Sign(claims)
+}
+
+// ValidateToken 校验令牌签名和有效期，签名密钥按令牌头中的 kid 选择
+func ValidateToken(tokenString string, keys *KeySet) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.Keyfunc)
 
 	if err != nil {
 		return nil, err
@@ -45,8 +63,8 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
-func ExtractUserID(tokenString, secret string) (uuid.UUID, error) {
-	claims, err := ValidateToken(tokenString, secret)
+func ExtractUserID(tokenString string, keys *KeySet) (uuid.UUID, error) {
+	claims, err := ValidateToken(tokenString, keys)
 	if err != nil {
 		return uuid.Nil, err
 	}
@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// activeKeyFile 密钥目录中指定签名密钥 kid 的文件，修改后下次重新加载即生效
+const activeKeyFile = "active"
+
+// KeySetOptions 签名密钥来源
+type KeySetOptions struct {
+	Dir           string // 密钥目录：<kid>.pem 为私钥（签名+验证），<kid>.pub.pem 为公钥（只用于验证）
+	PrivateKeyPEM string // 直接在配置中提供的私钥
+	KeyID         string // PrivateKeyPEM 的 kid
+	ActiveKeyID   string // 签名使用的 kid；留空时读取目录中的 active 文件，仍为空则取 kid 排序最大的私钥
+	HMACSecret    string // HS256 密钥；没有配置非对称密钥时用它签名
+	AllowHMAC     bool   // 配置了非对称密钥后是否仍接受 HS256 令牌（迁移期使用）
+}
+
+// verificationKey 用于验证的公钥
+type verificationKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// KeySet 当前可用的签名/验证密钥，支持 Ed25519 和 RS256，可在运行时重新加载
+type KeySet struct {
+	opts KeySetOptions
+
+	mu         sync.RWMutex
+	keys       map[string]verificationKey
+	activeID   string
+	activeKey  crypto.Signer
+	activeAlgo jwt.SigningMethod
+}
+
+// NewKeySet 按配置加载密钥
+func NewKeySet(opts KeySetOptions) (*KeySet, error) {
+	ks := &KeySet{opts: opts}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload 重新读取密钥。轮换时先放入新私钥（已发布到 JWKS 但不签名），
+// 再修改 active 文件切换签名密钥，旧令牌过期后再移除旧密钥，全程无需重启。
+// 读取失败时保留原有密钥
+func (ks *KeySet) Reload() error {
+	keys := make(map[string]verificationKey)
+	signers := make(map[string]crypto.Signer)
+
+	add := func(kid string, key interface{}) error {
+		if _, exists := keys[kid]; exists {
+			return fmt.Errorf("duplicate key id %q", kid)
+		}
+		method, public, err := keyMethod(key)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", kid, err)
+		}
+		keys[kid] = verificationKey{method: method, public: public}
+		if signer, ok := key.(crypto.Signer); ok {
+			signers[kid] = signer
+		}
+		return nil
+	}
+
+	if ks.opts.PrivateKeyPEM != "" {
+		if ks.opts.KeyID == "" {
+			return errors.New("JWT_KEY_ID is required when JWT_PRIVATE_KEY is set")
+		}
+		key, err := parsePEMKey([]byte(ks.opts.PrivateKeyPEM))
+		if err != nil {
+			return fmt.Errorf("key %q: %w", ks.opts.KeyID, err)
+		}
+		if err := add(ks.opts.KeyID, key); err != nil {
+			return err
+		}
+	}
+
+	activeID := ks.opts.ActiveKeyID
+	if ks.opts.Dir != "" {
+		files, err := filepath.Glob(filepath.Join(ks.opts.Dir, "*.pem"))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			key, err := parsePEMKey(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			kid := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".pem"), ".pub")
+			if err := add(kid, key); err != nil {
+				return err
+			}
+		}
+
+		if activeID == "" {
+			data, err := os.ReadFile(filepath.Join(ks.opts.Dir, activeKeyFile))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			activeID = strings.TrimSpace(string(data))
+		}
+	}
+
+	if activeID == "" && len(signers) > 0 {
+		ids := make([]string, 0, len(signers))
+		for kid := range signers {
+			ids = append(ids, kid)
+		}
+		sort.Strings(ids)
+		activeID = ids[len(ids)-1]
+	}
+
+	var active crypto.Signer
+	if activeID != "" {
+		var ok bool
+		if active, ok = signers[activeID]; !ok {
+			return fmt.Errorf("active key %q not found or has no private key", activeID)
+		}
+	}
+	if active == nil && ks.opts.HMACSecret == "" {
+		return errors.New("no signing key configured")
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+	ks.activeID = activeID
+	ks.activeKey = active
+	if active != nil {
+		ks.activeAlgo = keys[activeID].method
+	} else {
+		ks.activeAlgo = nil
+	}
+	return nil
+}
+
+// Asymmetric 是否使用非对称密钥签名
+func (ks *KeySet) Asymmetric() bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.activeKey != nil
+}
+
+// Sign 使用当前签名密钥签发令牌（非对称密钥会写入 kid）
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	kid, key, method := ks.activeID, ks.activeKey, ks.activeAlgo
+	ks.mu.RUnlock()
+
+	if key == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(ks.opts.HMACSecret))
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+// Keyfunc 供 jwt.Parse 使用：按 kid 选择公钥，并要求算法与密钥类型一致
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if ks.opts.HMACSecret == "" || (ks.activeKey != nil && !ks.opts.AllowHMAC) {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(ks.opts.HMACSecret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.public, nil
+}
+
+// JWK 单个公钥（RSA 或 Ed25519）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 导出全部公钥，供其他服务验证令牌（HS256 密钥不会导出）
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	ids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		ids = append(ids, kid)
+	}
+	sort.Strings(ids)
+
+	set := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, kid := range ids {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv, jwk.X = "OKP", "Ed25519", base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// keyMethod 根据密钥类型确定签名算法和公钥
+func keyMethod(key interface{}) (jwt.SigningMethod, crypto.PublicKey, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, k.Public(), nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, k, nil
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256, &k.PublicKey, nil
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, k, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// parsePEMKey 解析 PEM 格式的私钥（PKCS#8 / PKCS#1）或公钥（PKIX）
+func parsePEMKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret RFC 6238 附录 B 中 SHA1 的测试密钥 "12345678901234567890"（base32 编码）
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 附录 B 的 SHA1 测试向量，取 8 位结果的后 6 位
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, tc := range rfc6238Vectors {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("T=%d: %v", tc.unix, err)
+		}
+		if code != tc.code {
+			t.Errorf("T=%d: got %s, want %s", tc.unix, code, tc.code)
+		}
+	}
+}
+
+func TestTOTPCodeNormalizesSecret(t *testing.T) {
+	code, err := TOTPCode(" gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", TOTPStep(time.Unix(59, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "287082" {
+		t.Errorf("got %s, want 287082", code)
+	}
+}
+
+func TestTOTPCodeInvalidSecret(t *testing.T) {
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("expected error for invalid secret")
+	}
+}
+
+func TestTOTPStep(t *testing.T) {
+	tests := []struct {
+		unix int64
+		step int64
+	}{
+		{0, 0},
+		{29, 0},
+		{30, 1},
+		{59, 1},
+		{60, 2},
+		{1111111109, 37037036},
+	}
+	for _, tc := range tests {
+		if got := TOTPStep(time.Unix(tc.unix, 0)); got != tc.step {
+			t.Errorf("T=%d: got step %d, want %d", tc.unix, got, tc.step)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+	codeAt := func(step int64) string {
+		code, err := TOTPCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", codeAt(current), 1, current, true},
+		{"previous step within skew", codeAt(current - 1), 1, current - 1, true},
+		{"next step within skew", codeAt(current + 1), 1, current + 1, true},
+		{"previous step without skew", codeAt(current - 1), 0, 0, false},
+		{"outside skew", codeAt(current - 2), 1, 0, false},
+		{"surrounding whitespace", " " + codeAt(current) + " ", 1, current, true},
+		{"wrong code", "000000", 1, 0, false},
+		{"too short", codeAt(current)[:5], 1, 0, false},
+		{"too long", codeAt(current) + "0", 1, 0, false},
+		{"empty", "", 1, 0, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, tc.code, now, tc.skew)
+			if ok != tc.wantOK || step != tc.wantStep {
+				t.Errorf("got (%d, %v), want (%d, %v)", step, ok, tc.wantStep, tc.wantOK)
+			}
+		})
+	}
+}
+
+// 防重放依赖返回的步数：同一验证码在窗口内再次校验时返回相同的步数，
+// 调用方只接受大于上次使用步数的结果
+func TestValidateTOTPReplayStep(t *testing.T) {
+	issued := time.Unix(1111111111, 0)
+	code, err := TOTPCode(rfc6238Secret, TOTPStep(issued))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, ok := ValidateTOTP(rfc6238Secret, code, issued, 1)
+	if !ok {
+		t.Fatal("first use rejected")
+	}
+	replayed, ok := ValidateTOTP(rfc6238Secret, code, issued.Add(TOTPPeriod), 1)
+	if !ok {
+		t.Fatal("code within skew rejected")
+	}
+	if replayed != first {
+		t.Errorf("replayed code matched step %d, want %d", replayed, first)
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, code, issued.Add(2*TOTPPeriod), 1); ok {
+		t.Error("code accepted outside skew window")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("got secret length %d, want 32", len(secret))
+	}
+	if _, err := TOTPCode(secret, 1); err != nil {
+		t.Errorf("generated secret not usable: %v", err)
+	}
+}
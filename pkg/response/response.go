@@ -28,6 +28,15 @@ func Error(c *gin.Context, code int, message string) {
 	})
 }
 
+// ErrorWithData 返回错误并附带结构化数据（如机器可读的错误码）
+func ErrorWithData(c *gin.Context, code int, message string, data interface{}) {
+	c.JSON(http.StatusOK, Response{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	})
+}
+
 func BadRequest(c *gin.Context, message string) {
 	Error(c, 400, message)
 }